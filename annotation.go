@@ -0,0 +1,159 @@
+package openapi3Struct
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// annotation is a key/value pair written as key:"value" in struct tags and
+// doc comments, e.g. oapi_pattern:"^[a-z]+$". Flags like oapi_allOf have no value.
+type annotation struct {
+	key   string
+	value string
+}
+
+// parseStructTag returns the key:"value" pairs of a struct tag, following the
+// conventions of reflect.StructTag: keys are separated by spaces and values
+// are Go string literals.
+func parseStructTag(tag string) ([]annotation, error) {
+	annotations := []annotation{}
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return annotations, fmt.Errorf("malformed struct tag %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return annotations, fmt.Errorf("unterminated value of struct tag key %q", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return annotations, fmt.Errorf("invalid value of struct tag key %q: %w", key, err)
+		}
+		tag = tag[i+1:]
+		annotations = append(annotations, annotation{key: key, value: value})
+	}
+
+	return annotations, nil
+}
+
+// parseAnnotations returns the annotations of a doc comment line. A line holds
+// annotations separated by spaces, each is a key optionally followed by a colon
+// and a value. Values are either double quoted, in which case \" and \\ are
+// escapes and any other backslash is kept as is so patterns can be written
+// naturally, or bare, in which case the value runs until the next annotation
+// key or the end of the line:
+//
+//	oapi_discriminator:kind oapi_discriminator_mapped_parsed:oneOf
+//	oapi_pattern:"^[a-z]+\d$" oapi_example:"foo@bar.com"
+//	oapi_description: Hello, world.
+func parseAnnotations(line string) ([]annotation, error) {
+	annotations := []annotation{}
+	rest := strings.TrimSpace(line)
+	for rest != "" {
+		key := annotationKey(rest)
+		if key == "" {
+			return annotations, fmt.Errorf("expected annotation key at %q", rest)
+		}
+		rest = rest[len(key):]
+		if !strings.HasPrefix(rest, ":") {
+			if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+				return annotations, fmt.Errorf("unexpected %q after annotation key %q", rest, key)
+			}
+			annotations = append(annotations, annotation{key: key})
+			rest = strings.TrimSpace(rest)
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t")
+
+		var value string
+		var err error
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, err = quotedAnnotationValue(rest)
+			if err != nil {
+				return annotations, fmt.Errorf("invalid value of annotation %q: %w", key, err)
+			}
+			if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+				return annotations, fmt.Errorf("unexpected %q after value of annotation %q", rest, key)
+			}
+		} else {
+			value, rest = bareAnnotationValue(rest)
+		}
+		annotations = append(annotations, annotation{key: key, value: value})
+		rest = strings.TrimSpace(rest)
+	}
+
+	return annotations, nil
+}
+
+// annotationKey returns the key at the start of s, keys are made of letters,
+// digits, underscores and dashes.
+func annotationKey(s string) string {
+	i := 0
+	for i < len(s) {
+		c := rune(s[i])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '-' {
+			break
+		}
+		i++
+	}
+
+	return s[:i]
+}
+
+func quotedAnnotationValue(s string) (string, string, error) {
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return value.String(), s[i+1:], nil
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+				i++
+			}
+		}
+		value.WriteByte(s[i])
+	}
+
+	return "", "", errors.New("missing closing quote")
+}
+
+// bareAnnotationValue returns the unquoted value at the start of s, which runs
+// until the next "oapi...:" key preceded by a space or the end of s.
+func bareAnnotationValue(s string) (string, string) {
+	for i := 0; i < len(s); i++ {
+		if !unicode.IsSpace(rune(s[i])) {
+			continue
+		}
+		next := strings.TrimLeft(s[i:], " \t")
+		if key := annotationKey(next); strings.HasPrefix(key, "oapi") && strings.HasPrefix(next[len(key):], ":") {
+			return strings.TrimSpace(s[:i]), next
+		}
+	}
+
+	return strings.TrimSpace(s), ""
+}
@@ -0,0 +1,71 @@
+package openapi3Struct
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line     string
+		expected []annotation
+	}{
+		{
+			line:     `oapi_allOf`,
+			expected: []annotation{{key: "oapi_allOf"}},
+		},
+		{
+			line:     `oapi_discriminator:kind oapi_discriminator_mapped_parsed:oneOf`,
+			expected: []annotation{{key: "oapi_discriminator", value: "kind"}, {key: "oapi_discriminator_mapped_parsed", value: "oneOf"}},
+		},
+		{
+			line:     `oapi_pattern:"^[a-z]+\d$" oapi_example:"say \"hi\""`,
+			expected: []annotation{{key: "oapi_pattern", value: `^[a-z]+\d$`}, {key: "oapi_example", value: `say "hi"`}},
+		},
+		{
+			line:     `oapi_description: Hello, world. oapi_required:true`,
+			expected: []annotation{{key: "oapi_description", value: "Hello, world."}, {key: "oapi_required", value: "true"}},
+		},
+		{
+			line:     `oapi_oneOf: circle`,
+			expected: []annotation{{key: "oapi_oneOf", value: "circle"}},
+		},
+	}
+	for _, test := range tests {
+		annotations, err := parseAnnotations(test.line)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(annotations, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.line, test.expected, annotations)
+		}
+	}
+
+	if _, err := parseAnnotations(`oapi_pattern:"^[a-z]+`); err == nil {
+		t.Errorf("expected an error for an unterminated value")
+	}
+}
+
+func TestParseStructTag(t *testing.T) {
+	t.Parallel()
+
+	annotations, err := parseStructTag(`json:"email,omitempty" oapi_pattern:"^[a-z]+\\d$" oapi_example:"foo@bar.com"`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []annotation{
+		{key: "json", value: "email,omitempty"},
+		{key: "oapi_pattern", value: `^[a-z]+\d$`},
+		{key: "oapi_example", value: "foo@bar.com"},
+	}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Fatalf("expected %v, got %v", expected, annotations)
+	}
+
+	if _, err := parseStructTag(`json:name`); err == nil {
+		t.Fatalf("expected an error for an unquoted value")
+	}
+}
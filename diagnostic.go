@@ -0,0 +1,186 @@
+package openapi3Struct
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Severity tells whether a Diagnostic fails the generation.
+type Severity int
+
+const (
+	// SeverityError is a problem that fails the generation, like a malformed
+	// annotation or an annotation value which does not parse.
+	SeverityError Severity = iota
+	// SeverityWarning is a problem the generator works around, like a field
+	// of a type which has no JSON encoding. Warnings only fail the generation
+	// in strict mode.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a problem found in the source of the parsed packages.
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Message  string
+}
+
+// Error formats the diagnostic as file:line:column: severity: message.
+func (d Diagnostic) Error() string {
+	if d.Pos.Filename == "" && !d.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// Diagnostics is the error returned by ParseSchemasFromStructs when the
+// parsed packages hold errors, or warnings in strict mode.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the diagnostics as a list of errors, for errors.As and
+// errors.Is.
+func (d Diagnostics) Unwrap() []error {
+	errs := make([]error, len(d))
+	for i, diagnostic := range d {
+		errs[i] = diagnostic
+	}
+	return errs
+}
+
+// HasErrors reports whether any diagnostic is an error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// packageDiagnostic converts an error reported while loading packages, its
+// position is written as "file:line:col", "file:line", "" or "-".
+func packageDiagnostic(e packages.Error) Diagnostic {
+	diagnostic := Diagnostic{
+		Severity: SeverityError,
+		Message:  e.Msg,
+	}
+	if e.Pos == "" || e.Pos == "-" {
+		return diagnostic
+	}
+	parts := strings.Split(e.Pos, ":")
+	numbers := []int{}
+	for len(parts) > 1 && len(numbers) < 2 {
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		parts = parts[:len(parts)-1]
+	}
+	diagnostic.Pos.Filename = strings.Join(parts, ":")
+	if len(numbers) > 0 {
+		diagnostic.Pos.Line = numbers[0]
+	}
+	if len(numbers) > 1 {
+		diagnostic.Pos.Column = numbers[1]
+	}
+
+	return diagnostic
+}
+
+// report records a diagnostic at pos, a position in the files of pkg.
+func (r *schemaResolver) report(pkg *packages.Package, pos token.Pos, severity Severity, format string, args ...any) {
+	diagnostic := Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if pkg != nil && pkg.Fset != nil {
+		diagnostic.Pos = pkg.Fset.Position(pos)
+	}
+	r.diagnostics = append(r.diagnostics, diagnostic)
+}
+
+// annotationLine is a line of a comment together with its position.
+type annotationLine struct {
+	pos  token.Pos
+	text string
+}
+
+// commentLines splits a comment group into lines without the comment markers,
+// positioned at their first character so diagnostics point at the annotation.
+func commentLines(group *ast.CommentGroup) []annotationLine {
+	if group == nil {
+		return nil
+	}
+	lines := []annotationLine{}
+	for _, c := range group.List {
+		if text, ok := strings.CutPrefix(c.Text, "//"); ok {
+			trimmed := strings.TrimLeft(text, " \t")
+			offset := 2 + len(text) - len(trimmed)
+			lines = append(lines, annotationLine{pos: c.Pos() + token.Pos(offset), text: trimmed})
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, annotationLine{pos: c.Pos(), text: strings.TrimSpace(line)})
+		}
+	}
+	return lines
+}
+
+// unsupportedType returns the part of typ which has no JSON encoding, like
+// channels, functions and complex numbers, or nil when typ is supported.
+func unsupportedType(typ types.Type) types.Type {
+	return unsupportedTypeOf(typ, map[*types.Named]bool{})
+}
+
+// unsupportedTypeOf implements unsupportedType, visited holds the named types
+// already inspected so recursive types like type Tree map[string]Tree end.
+func unsupportedTypeOf(typ types.Type, visited map[*types.Named]bool) types.Type {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Named:
+		if _, ok := t.Underlying().(*types.Struct); ok || visited[t] {
+			return nil
+		}
+		visited[t] = true
+		if unsupportedTypeOf(t.Underlying(), visited) != nil {
+			return t
+		}
+	case *types.Pointer:
+		return unsupportedTypeOf(t.Elem(), visited)
+	case *types.Slice:
+		return unsupportedTypeOf(t.Elem(), visited)
+	case *types.Array:
+		return unsupportedTypeOf(t.Elem(), visited)
+	case *types.Map:
+		return unsupportedTypeOf(t.Elem(), visited)
+	case *types.Chan, *types.Signature:
+		return t
+	case *types.Basic:
+		if t.Info()&types.IsComplex != 0 || t.Kind() == types.UnsafePointer {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Content types commonly documented next to application/json.
+const (
+	ContentTypeJSON      = "application/json"
+	ContentTypeXML       = "application/xml"
+	ContentTypeForm      = "application/x-www-form-urlencoded"
+	ContentTypeMultipart = "multipart/form-data"
+	ContentTypeCSV       = "text/csv"
+	ContentTypeBinary    = "application/octet-stream"
+)
+
+var (
+	readerType     = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// MediaTypeOption sets the examples and encodings of a media type.
+type MediaTypeOption func(mt *openapi3.MediaType)
+
+// WithExample sets the example of a media type.
+func WithExample(value any) MediaTypeOption {
+	return func(mt *openapi3.MediaType) {
+		mt.Example = value
+	}
+}
+
+// WithNamedExample adds an example named name to a media type.
+func WithNamedExample(name, summary string, value any) MediaTypeOption {
+	return func(mt *openapi3.MediaType) {
+		if mt.Examples == nil {
+			mt.Examples = openapi3.Examples{}
+		}
+		example := openapi3.NewExample(value)
+		example.Summary = summary
+		mt.Examples[name] = &openapi3.ExampleRef{Value: example}
+	}
+}
+
+// WithEncoding sets the encoding of the property of a multipart or form
+// media type.
+func WithEncoding(property string, encoding *openapi3.Encoding) MediaTypeOption {
+	return func(mt *openapi3.MediaType) {
+		if mt.Encoding == nil {
+			mt.Encoding = map[string]*openapi3.Encoding{}
+		}
+		mt.Encoding[property] = encoding
+	}
+}
+
+// WithRequestBodyContent adds the content type to the request body, next to
+// the ones already added, with the schema of bodyType. io.Reader and
+// *multipart.FileHeader are binary strings, like []byte unless the content
+// type is JSON, other named types are refs to their component. A nil
+// bodyType leaves the schema out.
+func (ob *OperationBuilder) WithRequestBodyContent(contentType string, bodyType any, options ...MediaTypeOption) *OperationBuilder {
+	body := ob.requestBody()
+	body.Content[contentType] = ob.mediaType(contentType, bodyType, options)
+	return ob
+}
+
+// WithRequestBodyDescription sets the description and requirement of the
+// request body, whatever content types it has.
+func (ob *OperationBuilder) WithRequestBodyDescription(description string, required bool) *OperationBuilder {
+	body := ob.requestBody()
+	body.Description = description
+	body.Required = required
+	return ob
+}
+
+// WithResponseContent adds the content type to the response of statusCode,
+// next to the ones already added, with the schema of responseType like
+// WithRequestBodyContent.
+func (ob *OperationBuilder) WithResponseContent(statusCode int, contentType string, responseType any, options ...MediaTypeOption) *OperationBuilder {
+	return ob.WithResponseCodeContent(strconv.Itoa(statusCode), contentType, responseType, options...)
+}
+
+// WithResponseCodeContent adds the content type to the response of code, a
+// status code, a range of status codes like 4XX, or default, like
+// WithResponseContent.
+func (ob *OperationBuilder) WithResponseCodeContent(code, contentType string, responseType any, options ...MediaTypeOption) *OperationBuilder {
+	if !isResponseCode(code) {
+		fmt.Printf("Warning: response code '%s' is ignored, expected a status code, a range like 4XX or default\n", code)
+		return ob
+	}
+	res, ok := ob.responses[code]
+	if !ok {
+		res = &openapi3.ResponseRef{
+			Value: &openapi3.Response{
+				Description: ToPointer(""),
+			},
+		}
+		ob.responses[code] = res
+	}
+	if res.Value.Content == nil {
+		res.Value.Content = openapi3.Content{}
+	}
+	res.Value.Content[contentType] = ob.mediaType(contentType, responseType, options)
+	return ob
+}
+
+// WithFileUpload documents a multipart/form-data request body with the fields
+// of the struct formType as parts, named by their form or json tag. Fields of
+// type []byte, io.Reader and *multipart.FileHeader, or slices of them, are
+// binary files encoded as application/octet-stream.
+func (ob *OperationBuilder) WithFileUpload(formType any, description string, required bool, options ...MediaTypeOption) *OperationBuilder {
+	typ := reflect.TypeOf(formType)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		fmt.Printf("Warning: file upload form must be a struct, got %T\n", formType)
+		return ob
+	}
+
+	schema := openapi3.NewObjectSchema()
+	mt := &openapi3.MediaType{
+		Schema: openapi3.NewSchemaRef("", schema),
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, ok := formFieldName(field)
+		if !ok {
+			continue
+		}
+		schema.WithPropertyRef(name, ob.schemaRef(field.Type, true))
+		if isBinary(field.Type) || (field.Type.Kind() == reflect.Slice && isBinary(field.Type.Elem())) {
+			WithEncoding(name, &openapi3.Encoding{ContentType: ContentTypeBinary})(mt)
+		}
+	}
+	for _, option := range options {
+		option(mt)
+	}
+
+	body := ob.requestBody()
+	body.Description = description
+	body.Required = required
+	body.Content[ContentTypeMultipart] = mt
+	return ob
+}
+
+func (ob *OperationBuilder) requestBody() *openapi3.RequestBody {
+	if ob.op.RequestBody == nil || ob.op.RequestBody.Value == nil {
+		ob.op.RequestBody = &openapi3.RequestBodyRef{
+			Value: &openapi3.RequestBody{},
+		}
+	}
+	if ob.op.RequestBody.Value.Content == nil {
+		ob.op.RequestBody.Value.Content = openapi3.Content{}
+	}
+	return ob.op.RequestBody.Value
+}
+
+func (ob *OperationBuilder) mediaType(contentType string, bodyType any, options []MediaTypeOption) *openapi3.MediaType {
+	mt := &openapi3.MediaType{}
+	if bodyType != nil {
+		mt.Schema = ob.schemaRef(reflect.TypeOf(bodyType), !isJSON(contentType))
+	}
+	for _, option := range options {
+		option(mt)
+	}
+	return mt
+}
+
+// formFieldName returns the part name of a form field, from its form or json
+// tag or its name, and false for fields which are not encoded.
+func formFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	for _, key := range []string{"form", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return field.Name, true
+}
+
+// isJSON reports whether contentType, like application/json or
+// application/problem+json, encodes values as JSON.
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// isBinary reports whether values of typ are sent as raw bytes in a
+// multipart part.
+func isBinary(typ reflect.Type) bool {
+	if typ == fileHeaderType {
+		return true
+	}
+	if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+		return true
+	}
+	return typ.Implements(readerType)
+}
+
+// TypeRef is a $ref to the component of a Go type in the operation of a
+// builder. The ref follows the default naming of the generator, the parser
+// renames it after its naming options and type mappings.
+type TypeRef struct {
+	Schema *openapi3.SchemaRef
+	Type   reflect.Type
+	// Inline is the schema of the kind of Type, for basic, slice, array and
+	// map types, used when no component is generated for Type
+	Inline *openapi3.SchemaRef
+}
+
+// TypeRefs returns the $refs to the components of Go types in the operation.
+func (ob *OperationBuilder) TypeRefs() []TypeRef {
+	return ob.typeRefs
+}
+
+// schemaRef returns the schema of values of typ, shared by request bodies,
+// responses, headers and parameters. []byte is a base64 string, or a binary
+// string when raw. Named types refer to their component and are recorded as
+// TypeRefs, well known types like time.Time are described inline like the
+// generator does until the parser resolves them. Remaining types are
+// described inline.
+func (ob *OperationBuilder) schemaRef(typ reflect.Type, raw bool) *openapi3.SchemaRef {
+	if typ == fileHeaderType || typ.Implements(readerType) {
+		return openapi3.NewSchemaRef("", openapi3.NewStringSchema().WithFormat("binary"))
+	}
+	if typ.Kind() == reflect.Ptr {
+		return ob.schemaRef(typ.Elem(), raw)
+	}
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		ref := openapi3.NewSchemaRef(fmt.Sprintf("#/components/schemas/%s", componentName(typ.Name())), nil)
+		if schema, ok := TypeMapping(typ.PkgPath() + "." + typ.Name()); ok {
+			ref = openapi3.NewSchemaRef("", schema)
+		}
+		typeRef := TypeRef{Schema: ref, Type: typ}
+		switch typ.Kind() {
+		case reflect.Struct, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		default:
+			typeRef.Inline = ob.kindSchemaRef(typ, raw)
+		}
+		ob.typeRefs = append(ob.typeRefs, typeRef)
+		return ref
+	}
+
+	return ob.kindSchemaRef(typ, raw)
+}
+
+// kindSchemaRef returns the inline schema of values of typ, after its kind.
+func (ob *OperationBuilder) kindSchemaRef(typ reflect.Type, raw bool) *openapi3.SchemaRef {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return ob.schemaRef(typ.Elem(), raw)
+	case reflect.String:
+		return openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+	case reflect.Bool:
+		return openapi3.NewSchemaRef("", openapi3.NewBoolSchema())
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return openapi3.NewSchemaRef("", openapi3.NewInt32Schema())
+	case reflect.Int, reflect.Int64:
+		return openapi3.NewSchemaRef("", openapi3.NewInt64Schema())
+	case reflect.Uint8, reflect.Uint16:
+		return openapi3.NewSchemaRef("", openapi3.NewInt32Schema().WithMin(0))
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return openapi3.NewSchemaRef("", openapi3.NewInt64Schema().WithMin(0))
+	case reflect.Float32:
+		return openapi3.NewSchemaRef("", openapi3.NewFloat64Schema().WithFormat("float"))
+	case reflect.Float64:
+		return openapi3.NewSchemaRef("", openapi3.NewFloat64Schema().WithFormat("double"))
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if raw {
+				return openapi3.NewSchemaRef("", openapi3.NewStringSchema().WithFormat("binary"))
+			}
+			return openapi3.NewSchemaRef("", openapi3.NewBytesSchema())
+		}
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:  &openapi3.Types{openapi3.TypeArray},
+			Items: ob.schemaRef(typ.Elem(), raw),
+		})
+	case reflect.Array:
+		length := uint64(typ.Len())
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:     &openapi3.Types{openapi3.TypeArray},
+			Items:    ob.schemaRef(typ.Elem(), raw),
+			MinItems: length,
+			MaxItems: &length,
+		})
+	case reflect.Map:
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:                 &openapi3.Types{openapi3.TypeObject},
+			AdditionalProperties: openapi3.AdditionalProperties{Schema: ob.schemaRef(typ.Elem(), raw)},
+		})
+	}
+
+	return openapi3.NewSchemaRef("", openapi3.NewObjectSchema())
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -29,14 +32,17 @@ func ToPointer[T any](v T) *T {
 }
 
 type OperationBuilder struct {
-	op        *openapi3.Operation
-	responses map[int]*openapi3.ResponseRef
+	op *openapi3.Operation
+	// responses are keyed by status code, range like 4XX or default
+	responses map[string]*openapi3.ResponseRef
+	// typeRefs are the $refs to the components of Go types
+	typeRefs []TypeRef
 }
 
 func NewOperationBuilder() *OperationBuilder {
 	return &OperationBuilder{
 		op:        &openapi3.Operation{},
-		responses: make(map[int]*openapi3.ResponseRef),
+		responses: make(map[string]*openapi3.ResponseRef),
 	}
 }
 
@@ -50,95 +56,122 @@ func (ob *OperationBuilder) WithDescription(desc string) *OperationBuilder {
 	return ob
 }
 
-func (ob *OperationBuilder) WithRequestBodyType(bodyType any, description string, required bool) *OperationBuilder {
-	if bodyType == nil {
-		fmt.Println("Warning: bodyType is nil for request body.")
-		ob.op.RequestBody = &openapi3.RequestBodyRef{
-			Value: &openapi3.RequestBody{
-				Required:    required,
-				Description: description,
-				Content: map[string]*openapi3.MediaType{
-					"application/json": {
-						Schema: openapi3.NewSchemaRef("", nil),
-					},
-				},
-			},
-		}
-		return ob
+func (ob *OperationBuilder) WithSummary(summary string) *OperationBuilder {
+	ob.op.Summary = summary
+	return ob
+}
+
+// WithOperationID sets the operationId, otherwise it is generated from the
+// method and path by the OperationIDStrategy of the parser.
+func (ob *OperationBuilder) WithOperationID(id string) *OperationBuilder {
+	ob.op.OperationID = id
+	return ob
+}
+
+func (ob *OperationBuilder) WithDeprecated(deprecated bool) *OperationBuilder {
+	ob.op.Deprecated = deprecated
+	return ob
+}
+
+// WithSecurity adds a security requirement, met by the security scheme name
+// with the given scopes. Requirements added by successive calls are
+// alternatives, any of them grants access to the operation.
+func (ob *OperationBuilder) WithSecurity(name string, scopes ...string) *OperationBuilder {
+	if ob.op.Security == nil {
+		ob.op.Security = openapi3.NewSecurityRequirements()
 	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	ob.op.Security.With(openapi3.SecurityRequirement{name: scopes})
+	return ob
+}
 
-	typ := reflect.TypeOf(bodyType)
-	isSlice := typ.Kind() == reflect.Slice
-	var itemTypeName string
-	var schemaRef *openapi3.SchemaRef
+// WithoutSecurity makes the operation public, overriding the security
+// requirements of the document.
+func (ob *OperationBuilder) WithoutSecurity() *OperationBuilder {
+	ob.op.Security = openapi3.NewSecurityRequirements()
+	return ob
+}
 
-	if isSlice {
-		elemType := typ.Elem()
-		if elemType.Kind() == reflect.Ptr {
-			elemType = elemType.Elem()
-		}
-		itemTypeName = elemType.Name()
-		if itemTypeName == "" {
-			fmt.Printf("Warning: Could not determine element type name for request body array: %T\n", bodyType)
-			return ob
-		}
-		schemaRef = &openapi3.SchemaRef{
-			Value: &openapi3.Schema{
-				Type: openapi3.NewArraySchema().Type,
-				Items: &openapi3.SchemaRef{
-					Ref: fmt.Sprintf("#/components/schemas/%s", itemTypeName),
-				},
-			},
-		}
-	} else {
-		itemTypeName = GetTypeName(bodyType)
-		if itemTypeName == "" {
-			fmt.Printf("Warning: Could not determine type name for request body: %T\n", bodyType)
-			return ob
-		}
-		schemaRef = &openapi3.SchemaRef{
-			Ref: fmt.Sprintf("#/components/schemas/%s", itemTypeName),
-		}
+// WithServer adds a server serving the operation, overriding the servers of
+// the document and path.
+func (ob *OperationBuilder) WithServer(url, description string) *OperationBuilder {
+	if ob.op.Servers == nil {
+		ob.op.Servers = &openapi3.Servers{}
 	}
+	*ob.op.Servers = append(*ob.op.Servers, &openapi3.Server{
+		URL:         url,
+		Description: description,
+	})
+	return ob
+}
 
-	ob.op.RequestBody = &openapi3.RequestBodyRef{
-		Value: &openapi3.RequestBody{
-			Required:    required,
-			Description: description,
-			Content: map[string]*openapi3.MediaType{
-				"application/json": {
-					Schema: schemaRef,
-				},
-			},
-		},
+func (ob *OperationBuilder) WithExternalDocs(url, description string) *OperationBuilder {
+	ob.op.ExternalDocs = &openapi3.ExternalDocs{
+		URL:         url,
+		Description: description,
 	}
 	return ob
 }
 
-func (ob *OperationBuilder) WithResponse(statusCode int, description string, responseType any) *OperationBuilder {
-	var schemaRef *openapi3.SchemaRef
-	var content map[string]*openapi3.MediaType
+// WithExtension sets the specification extension name, which must start
+// with "x-".
+func (ob *OperationBuilder) WithExtension(name string, value any) *OperationBuilder {
+	if !strings.HasPrefix(name, "x-") {
+		fmt.Printf("Warning: extension '%s' is ignored, extension names must start with x-\n", name)
+		return ob
+	}
+	if ob.op.Extensions == nil {
+		ob.op.Extensions = map[string]any{}
+	}
+	ob.op.Extensions[name] = value
+	return ob
+}
+
+// WithRequestBodyType sets the JSON content of the request body to the schema
+// of bodyType, resolved like the types of responses and parameters.
+func (ob *OperationBuilder) WithRequestBodyType(bodyType any, description string, required bool) *OperationBuilder {
+	if bodyType == nil {
+		fmt.Println("Warning: bodyType is nil for request body.")
+	}
+	body := ob.requestBody()
+	body.Description = description
+	body.Required = required
+	body.Content[ContentTypeJSON] = ob.mediaType(ContentTypeJSON, bodyType, nil)
+	return ob
+}
+
+// WithResponse sets the response of statusCode, with a JSON body of
+// responseType unless it is nil. Options add headers, links and examples.
+func (ob *OperationBuilder) WithResponse(statusCode int, description string, responseType any, options ...ResponseOption) *OperationBuilder {
+	return ob.WithResponseCode(strconv.Itoa(statusCode), description, responseType, options...)
+}
 
+// WithResponseCode sets the response of code, a status code, a range of
+// status codes like 4XX, or default for the responses of all other codes.
+func (ob *OperationBuilder) WithResponseCode(code, description string, responseType any, options ...ResponseOption) *OperationBuilder {
+	if !isResponseCode(code) {
+		fmt.Printf("Warning: response code '%s' is ignored, expected a status code, a range like 4XX or default\n", code)
+		return ob
+	}
+
+	var content openapi3.Content
 	if responseType != nil {
-		typeName := GetTypeName(responseType)
-		if typeName == "" {
-			fmt.Printf("Warning: Could not determine type name for response type: %T\n", responseType)
-			schemaRef = openapi3.NewSchemaRef("", nil) // Empty schema
-		} else {
-			schemaRef = openapi3.NewSchemaRef(fmt.Sprintf("#/components/schemas/%s", typeName), nil)
-		}
-		content = map[string]*openapi3.MediaType{
-			"application/json": {
-				Schema: schemaRef,
-			},
+		content = openapi3.Content{
+			ContentTypeJSON: ob.mediaType(ContentTypeJSON, responseType, nil),
 		}
 	}
 
-	ob.responses[statusCode] = &openapi3.ResponseRef{
-		Value: &openapi3.Response{
-			Description: ToPointer(description),
-			Content:     content,
-		},
+	res := &openapi3.Response{
+		Description: ToPointer(description),
+		Content:     content,
+	}
+	for _, option := range options {
+		option(ob, res)
+	}
+	ob.responses[code] = &openapi3.ResponseRef{
+		Value: res,
 	}
 	return ob
 }
@@ -151,28 +184,8 @@ func (ob *OperationBuilder) WithParameter(name, in, description string, required
 		Required:    required,
 	}
 
-	// WIP - it'll be improved once i start working on hermes
 	if schemaType != nil {
-		typeName := GetTypeName(schemaType)
-		var paramSchema *openapi3.Schema
-		if typeName == "" {
-			switch reflect.TypeOf(schemaType).Kind() {
-			case reflect.String:
-				paramSchema = openapi3.NewStringSchema()
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				paramSchema = openapi3.NewInt64Schema()
-			case reflect.Float32, reflect.Float64:
-				paramSchema = openapi3.NewFloat64Schema()
-			case reflect.Bool:
-				paramSchema = openapi3.NewBoolSchema()
-			default:
-				fmt.Printf("Warning: Could not determine primitive OpenAPI schema for parameter '%s' type: %T. Schema will be empty.\n", name, schemaType)
-				paramSchema = openapi3.NewSchema()
-			}
-			param.Schema = openapi3.NewSchemaRef("", paramSchema)
-		} else {
-			param.Schema = openapi3.NewSchemaRef(fmt.Sprintf("#/components/schemas/%s", typeName), nil)
-		}
+		param.Schema = ob.schemaRef(reflect.TypeOf(schemaType), false)
 	} else {
 		param.Schema = openapi3.NewSchemaRef("", nil)
 	}
@@ -192,13 +205,62 @@ func (ob *OperationBuilder) WithParameterRef(ref string) *OperationBuilder {
 
 func (ob *OperationBuilder) Build() *openapi3.Operation {
 	responses := []openapi3.NewResponsesOption{}
-	for status, res := range ob.responses {
-		responses = append(responses, openapi3.WithStatus(status, res))
+	for code, res := range ob.responses {
+		responses = append(responses, openapi3.WithName(code, res.Value))
 	}
 	ob.op.Responses = openapi3.NewResponses(responses...)
 	return ob.op
 }
 
+// OperationIDStrategy generates the operationId of an operation which does
+// not set one, from its method and path.
+type OperationIDStrategy func(method, path string) string
+
+// DefaultOperationID joins the lower case method and the words of the path in
+// camel case, path parameters are prefixed with By, so GET /v1/orders/{orderId}
+// becomes getV1OrdersByOrderId.
+func DefaultOperationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if param, ok := strings.CutPrefix(segment, "{"); ok {
+			id += "By"
+			segment = strings.TrimSuffix(param, "}")
+		}
+		words := strings.FieldsFunc(segment, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			runes := []rune(word)
+			id += string(unicode.ToUpper(runes[0])) + string(runes[1:])
+		}
+	}
+	return id
+}
+
+// methods are the HTTP methods a path item holds an operation for
+var methods = []string{
+	http.MethodConnect,
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodTrace,
+}
+
+// IsMethod reports whether method, in any case, is an HTTP method an
+// operation can be documented for.
+func IsMethod(method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
 type HandlerProvider interface{}
 
 type EndpointDoc struct {
@@ -222,20 +284,10 @@ func (ep *EndpointDoc) GetPath() string {
 func (ep *EndpointDoc) BuildOpenAPiStruct() Path {
 	item := openapi3.PathItem{}
 	op := ep.PathItem.Build()
-	switch ep.Method {
-	case http.MethodPost:
-		item.Post = op
-	case http.MethodGet:
-		item.Get = op
-	case http.MethodPut:
-		item.Put = op
-	case http.MethodDelete:
-		item.Delete = op
-	case http.MethodOptions:
-		item.Options = op
-	default:
+	if !IsMethod(ep.Method) {
 		panic(fmt.Sprintf("Unknown request method: %s", ep.Method))
 	}
+	item.SetOperation(strings.ToUpper(ep.Method), op)
 	return Path{
 		Path: ep.GetPath(),
 		Item: item,
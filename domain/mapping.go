@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// typeMappings maps well known types, keyed by "import/path.Type", to the
+// schema matching their JSON encoding. The generator and the operation
+// builder share it so both describe these types inline.
+var typeMappings = map[string]*openapi3.Schema{
+	"time.Time":                                 openapi3.NewDateTimeSchema(),
+	"time.Duration":                             openapi3.NewInt64Schema(),
+	"encoding/json.RawMessage":                  openapi3.NewSchema(),
+	"encoding/json/jsontext.Value":              openapi3.NewSchema(),
+	"encoding/json.Number":                      openapi3.NewFloat64Schema(),
+	"github.com/google/uuid.UUID":               openapi3.NewUUIDSchema(),
+	"github.com/gofrs/uuid.UUID":                openapi3.NewUUIDSchema(),
+	"github.com/gofrs/uuid/v5.UUID":             openapi3.NewUUIDSchema(),
+	"github.com/satori/go.uuid.UUID":            openapi3.NewUUIDSchema(),
+	"net.IP":                                    openapi3.NewStringSchema().WithFormat("ip"),
+	"net/netip.Addr":                            openapi3.NewStringSchema().WithFormat("ip"),
+	"net/url.URL":                               openapi3.NewStringSchema().WithFormat("uri"),
+	"math/big.Int":                              openapi3.NewIntegerSchema(),
+	"github.com/shopspring/decimal.Decimal":     openapi3.NewStringSchema().WithFormat("decimal"),
+	"github.com/shopspring/decimal.NullDecimal": openapi3.NewStringSchema().WithFormat("decimal"),
+}
+
+// TypeMapping returns a copy of the built-in schema of the well known type
+// typeName, written "import/path.Type", like time.Time or
+// github.com/google/uuid.UUID.
+func TypeMapping(typeName string) (*openapi3.Schema, bool) {
+	schema, ok := typeMappings[typeName]
+	if !ok {
+		return nil, false
+	}
+	copied := *schema
+	return &copied, true
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"strings"
+)
+
+// componentName returns the component name the generator gives the type
+// named name by reflect, following DefaultGenericNamer for instantiated
+// generic types, so Page[example.com/api.User] becomes PageUser.
+func componentName(name string) string {
+	base, args, ok := strings.Cut(name, "[")
+	if !ok {
+		return name
+	}
+	args = strings.TrimSuffix(args, "]")
+	for _, arg := range splitTypeArgs(args) {
+		base += typeArgName(arg)
+	}
+	return base
+}
+
+// typeArgName names a type argument written by reflect, like
+// []example.com/api.User or map[string]int, following the generator.
+func typeArgName(arg string) string {
+	arg = strings.TrimSpace(arg)
+	switch {
+	case strings.HasPrefix(arg, "*"):
+		return typeArgName(arg[1:])
+	case strings.HasPrefix(arg, "[]"):
+		return typeArgName(arg[2:]) + "List"
+	case strings.HasPrefix(arg, "["):
+		_, elem, _ := strings.Cut(arg, "]")
+		return typeArgName(elem) + "List"
+	case strings.HasPrefix(arg, "map["):
+		key, elem := splitMapType(arg[len("map["):])
+		return typeArgName(key) + typeArgName(elem) + "Map"
+	case strings.HasPrefix(arg, "interface {") || strings.HasPrefix(arg, "struct {") || strings.HasPrefix(arg, "func(") || strings.HasPrefix(arg, "chan "):
+		return "Object"
+	}
+
+	// strip the import path of a qualified name, keeping its type arguments
+	base, args, generic := strings.Cut(arg, "[")
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[i+1:]
+	}
+	if i := strings.LastIndex(base, "."); i >= 0 {
+		base = base[i+1:]
+	} else if !generic {
+		return capitalize(base)
+	}
+	if generic {
+		return componentName(base + "[" + args)
+	}
+	return base
+}
+
+// splitTypeArgs splits a list of type arguments at the commas outside of
+// brackets.
+func splitTypeArgs(args string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, c := range args {
+		switch c {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, args[start:])
+}
+
+// splitMapType splits "K]V", the rest of a map type after "map[", into its key
+// and element types.
+func splitMapType(s string) (string, string) {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return s[:i], s[i+1:]
+			}
+			depth--
+		}
+	}
+	return s, ""
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ResponseOption adds headers, links and examples to a response.
+type ResponseOption func(ob *OperationBuilder, res *openapi3.Response)
+
+// WithHeader adds the header name to a response, like Location or ETag, with
+// the schema of headerType.
+func WithHeader(name, description string, headerType any, required bool) ResponseOption {
+	return func(ob *OperationBuilder, res *openapi3.Response) {
+		header := &openapi3.Header{
+			Parameter: openapi3.Parameter{
+				Description: description,
+				Required:    required,
+			},
+		}
+		if headerType != nil {
+			header.Schema = ob.schemaRef(reflect.TypeOf(headerType), false)
+		}
+		if res.Headers == nil {
+			res.Headers = openapi3.Headers{}
+		}
+		res.Headers[name] = &openapi3.HeaderRef{Value: header}
+	}
+}
+
+// WithLink adds a link named name to a response, pointing to the operation
+// operationID. Parameters map the parameters of the linked operation to
+// runtime expressions like $response.body#/id.
+func WithLink(name, operationID, description string, parameters map[string]any) ResponseOption {
+	return func(ob *OperationBuilder, res *openapi3.Response) {
+		if res.Links == nil {
+			res.Links = openapi3.Links{}
+		}
+		res.Links[name] = &openapi3.LinkRef{
+			Value: &openapi3.Link{
+				OperationID: operationID,
+				Description: description,
+				Parameters:  parameters,
+			},
+		}
+	}
+}
+
+// WithResponseExample adds an example named name to the JSON content of a
+// response. The value is marshaled to JSON, so the example follows the json
+// tags and MarshalJSON methods of its type.
+func WithResponseExample(name, summary string, value any) ResponseOption {
+	return func(ob *OperationBuilder, res *openapi3.Response) {
+		mt := res.Content.Get(ContentTypeJSON)
+		if mt == nil {
+			fmt.Printf("Warning: example '%s' is ignored, the response has no %s content\n", name, ContentTypeJSON)
+			return
+		}
+		example, err := jsonValue(value)
+		if err != nil {
+			fmt.Printf("Warning: example '%s' is ignored, it can not be marshaled to JSON: %v\n", name, err)
+			return
+		}
+		WithNamedExample(name, summary, example)(mt)
+	}
+}
+
+// jsonValue returns the JSON encoding of v decoded back into maps, slices and
+// basic values.
+func jsonValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// isResponseCode reports whether code is a status code, a range of status
+// codes like 4XX, or default.
+func isResponseCode(code string) bool {
+	if code == "default" {
+		return true
+	}
+	if len(code) != 3 || code[0] < '1' || code[0] > '5' {
+		return false
+	}
+	if code[1:] == "XX" {
+		return true
+	}
+	_, err := strconv.Atoi(code)
+	return err == nil
+}
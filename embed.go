@@ -0,0 +1,219 @@
+package openapi3Struct
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// jsonField is a field generated as a property of a struct schema, or as one
+// of its allOf or oneOf schemas when name is empty.
+type jsonField struct {
+	field  *types.Var
+	tag    string
+	syntax *ast.Field
+	pkg    *packages.Package
+	name   string
+	// optional is set for fields promoted through an embedded pointer, which
+	// encoding/json leaves out when the pointer is nil
+	optional bool
+	// index is the path of field indexes from the struct to the field
+	index []int
+}
+
+// jsonFields returns the fields of st, whose syntax is the one of syntax in
+// pkg, to generate properties for. When flattening embedded structs, the
+// fields of untagged embedded structs are promoted following the rules of
+// encoding/json, embedded structs annotated with oapi_allOf or oapi_oneOf are
+// kept for composition.
+func (r *schemaResolver) jsonFields(pkg *packages.Package, st *types.Struct, syntax *types.Struct) []jsonField {
+	if !r.opts.flattenEmbedded {
+		fields := []jsonField{}
+		astFields := r.structFields(syntax)
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if !field.Exported() && !isEmbeddedStruct(field) {
+				continue
+			}
+			jf := jsonField{field: field, tag: st.Tag(i), syntax: astFields[i], pkg: pkg, index: []int{i}}
+			if !field.Embedded() {
+				jf.name = field.Name()
+			}
+			if name := parseJSONTag(jf.tag).name; name != "" {
+				jf.name = name
+			}
+			fields = append(fields, jf)
+		}
+		return fields
+	}
+
+	type embedded struct {
+		st       *types.Struct
+		syntax   *types.Struct
+		optional bool
+		index    []int
+	}
+	var current []embedded
+	next := []embedded{{st: st, syntax: syntax}}
+	visited := map[*types.Struct]bool{}
+	// count holds how many times each struct is embedded at the current depth,
+	// the fields of a struct embedded twice shadow each other
+	count, nextCount := map[*types.Struct]int{}, map[*types.Struct]int{st: 1}
+	// candidates holds the fields of each name, by increasing depth
+	candidates := map[string][]jsonField{}
+	tagged := map[*types.Var]bool{}
+	fields := []jsonField{}
+	for len(next) > 0 {
+		current, next = next, nil
+		count, nextCount = nextCount, map[*types.Struct]int{}
+		for _, e := range current {
+			if visited[e.st] {
+				continue
+			}
+			visited[e.st] = true
+			fieldPkg := pkg
+			if declared, ok := r.structs[e.syntax]; ok {
+				fieldPkg = declared.pkg
+			}
+			astFields := r.structFields(e.syntax)
+			for i := 0; i < e.st.NumFields(); i++ {
+				field := e.st.Field(i)
+				tag := parseJSONTag(e.st.Tag(i))
+				if tag.skip {
+					continue
+				}
+				index := append(append([]int{}, e.index...), i)
+				jf := jsonField{field: field, tag: e.st.Tag(i), syntax: astFields[i], pkg: fieldPkg, optional: e.optional, index: index}
+				embeddedStruct, pointer := embeddedStructOf(field)
+				if field.Embedded() {
+					// encoding/json ignores unexported non struct embeds and
+					// pointers to unexported structs
+					if !field.Exported() && (embeddedStruct == nil || pointer) {
+						continue
+					}
+				} else if !field.Exported() {
+					continue
+				}
+				if embeddedStruct != nil && tag.name == "" {
+					named, _ := types.Unalias(derefType(field.Type())).(*types.Named)
+					// types with a mapped schema, like time.Time, marshal themselves
+					if hasFieldAnnotation(jf.syntax, "oapi_allOf") || hasFieldAnnotation(jf.syntax, "oapi_oneOf") || r.isMapped(named) {
+						fields = append(fields, jf)
+						continue
+					}
+					embeddedSyntax := embeddedStruct
+					if named != nil {
+						embeddedSyntax = r.syntaxStruct(named, embeddedStruct)
+					}
+					nextCount[embeddedStruct]++
+					if nextCount[embeddedStruct] == 1 {
+						next = append(next, embedded{st: embeddedStruct, syntax: embeddedSyntax, optional: e.optional || pointer, index: index})
+					}
+					continue
+				}
+				jf.name = field.Name()
+				if tag.name != "" {
+					jf.name = tag.name
+					tagged[field] = true
+				}
+				candidates[jf.name] = append(candidates[jf.name], jf)
+				if count[e.st] > 1 {
+					candidates[jf.name] = append(candidates[jf.name], jf)
+				}
+			}
+		}
+	}
+
+	for _, named := range candidates {
+		if dominant, ok := dominantField(named, tagged); ok {
+			fields = append(fields, dominant)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+
+	return fields
+}
+
+// dominantField returns the field of a name which encoding/json encodes: the
+// least nested one, or the only tagged one among the least nested. Fields
+// shadowing each other otherwise are all left out.
+func dominantField(fields []jsonField, tagged map[*types.Var]bool) (jsonField, bool) {
+	depth := len(fields[0].index)
+	dominant := []jsonField{}
+	for _, f := range fields {
+		if len(f.index) == depth {
+			dominant = append(dominant, f)
+		}
+	}
+	if len(dominant) == 1 {
+		return dominant[0], true
+	}
+	found := []jsonField{}
+	for _, f := range dominant {
+		if tagged[f.field] {
+			found = append(found, f)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+
+	return jsonField{}, false
+}
+
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// embeddedStructOf returns the struct type of an embedded field, and whether
+// it is embedded through a pointer, or nil when field does not embed a struct.
+func embeddedStructOf(field *types.Var) (*types.Struct, bool) {
+	if !field.Embedded() {
+		return nil, false
+	}
+	typ := types.Unalias(field.Type())
+	ptr, pointer := typ.(*types.Pointer)
+	if pointer {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil, false
+	}
+	return st, pointer
+}
+
+func derefType(typ types.Type) types.Type {
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return typ
+}
+
+// hasFieldAnnotation reports whether the doc comment of f holds the flag key
+func hasFieldAnnotation(f *ast.Field, key string) bool {
+	if f == nil {
+		return false
+	}
+	for _, line := range commentLines(f.Doc) {
+		if !isAnnotationLine(line.text) {
+			continue
+		}
+		annotations, _ := parseAnnotations(line.text)
+		for _, ann := range annotations {
+			if ann.key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package openapi3Struct
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
+)
+
+// enumConst is a constant declared with a named type, it becomes one of the
+// enum values of the schema of that type.
+type enumConst struct {
+	obj *types.Const
+	doc string
+}
+
+// addEnumConsts collects the constants of decl, a const declaration of pkg,
+// grouped by the named type they are declared with.
+func (r *schemaResolver) addEnumConsts(pkg *packages.Package, decl *ast.GenDecl) {
+	if decl.Tok != token.CONST {
+		return
+	}
+	for _, s := range decl.Specs {
+		spec, ok := s.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		doc := strings.TrimSpace(spec.Doc.Text())
+		if doc == "" {
+			doc = strings.TrimSpace(spec.Comment.Text())
+		}
+		for _, ident := range spec.Names {
+			c, ok := pkg.TypesInfo.Defs[ident].(*types.Const)
+			if !ok || c.Name() == "_" {
+				continue
+			}
+			named, ok := c.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			r.enums[named.Obj()] = append(r.enums[named.Obj()], enumConst{
+				obj: c,
+				doc: strings.Join(strings.Fields(doc), " "),
+			})
+		}
+	}
+}
+
+// applyEnum sets the enum values of schema from the constants declared with
+// the type obj, along with x-enum-varnames holding the constant names and
+// x-enum-descriptions holding their doc comments.
+func (r *schemaResolver) applyEnum(schema *openapi3.Schema, obj *types.TypeName) {
+	consts := r.enums[obj]
+	if len(consts) == 0 {
+		return
+	}
+	values := []any{}
+	varNames := []string{}
+	descriptions := []string{}
+	hasDescription := false
+	seen := map[any]bool{}
+	for _, c := range consts {
+		value, ok := enumValue(c.obj.Val())
+		if !ok || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+		varNames = append(varNames, c.obj.Name())
+		descriptions = append(descriptions, c.doc)
+		if c.doc != "" {
+			hasDescription = true
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	schema.Enum = values
+	if schema.Extensions == nil {
+		schema.Extensions = map[string]any{}
+	}
+	schema.Extensions["x-enum-varnames"] = varNames
+	if hasDescription {
+		schema.Extensions["x-enum-descriptions"] = descriptions
+	}
+}
+
+func enumValue(val constant.Value) (any, bool) {
+	switch val.Kind() {
+	case constant.String:
+		return constant.StringVal(val), true
+	case constant.Int:
+		if v, ok := constant.Int64Val(val); ok {
+			return v, true
+		}
+		if v, ok := constant.Uint64Val(val); ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,37 @@
+package openapi3Struct
+
+import (
+	"go/types"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/nextap-solutions/openapi3Struct/domain"
+)
+
+func typeMappingKey(obj *types.TypeName) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// mappedSchema returns a copy of the schema registered for obj, custom
+// mappings take precedence over the built-in ones.
+func (r *schemaResolver) mappedSchema(obj *types.TypeName) (*openapi3.Schema, bool) {
+	key := typeMappingKey(obj)
+	schema, ok := r.opts.typeMappings[key]
+	if !ok {
+		return domain.TypeMapping(key)
+	}
+	copied := *schema
+
+	return &copied, true
+}
+
+// isMapped reports whether named has a custom or built-in schema mapping
+func (r *schemaResolver) isMapped(named *types.Named) bool {
+	if named == nil {
+		return false
+	}
+	_, ok := r.mappedSchema(named.Obj())
+	return ok
+}
@@ -0,0 +1,273 @@
+package openapi3Struct
+
+import (
+	"fmt"
+	"go/types"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// NamingStrategy decides the component names of the generated schemas. A type
+// annotated with oapi_name:"PublicUser" is named PublicUser with every
+// strategy.
+type NamingStrategy int
+
+const (
+	// NamingBare names components after their type, e.g. User. Types with the
+	// same name in different packages conflict. This is the default strategy.
+	NamingBare NamingStrategy = iota
+	// NamingPackageQualified prefixes the type name with the name of its
+	// package, e.g. billing.User. Instantiated generic types are prefixed
+	// once, e.g. billing.PageUser.
+	NamingPackageQualified
+	// NamingImportPathHash suffixes the type name with a hash of the import
+	// path of its package, e.g. User_5f1e9c2a. Instantiated generic types are
+	// suffixed once, with a hash of the import paths of the generic type and
+	// its type arguments.
+	NamingImportPathHash
+	// NamingAuto names components after their type and only qualifies the
+	// names shared by types of different packages, with their package name or
+	// with the hash of their import path if the package names are equal too.
+	NamingAuto
+)
+
+// GenericNamer names the component of an instantiated generic type from the
+// bare names of the generic type and of its type arguments, e.g. "Page"
+// and ["User"] for Page[User].
+type GenericNamer func(name string, typeArgs []string) string
+
+// DefaultGenericNamer appends the type argument names to the type name, so
+// Page[User] becomes PageUser.
+func DefaultGenericNamer(name string, typeArgs []string) string {
+	return name + strings.Join(typeArgs, "")
+}
+
+// componentName returns the component schema name of a named type. Names are
+// unique while resolving in NamingAuto mode, disambiguate shortens them once
+// every component is known.
+func (r *schemaResolver) componentName(named *types.Named) string {
+	strategy := r.opts.namingStrategy
+	if strategy == NamingAuto {
+		strategy = NamingImportPathHash
+	}
+	return r.componentNameWith(named, strategy)
+}
+
+func (r *schemaResolver) componentNameWith(named *types.Named, strategy NamingStrategy) string {
+	obj := named.Obj()
+	if named.TypeArgs().Len() == 0 {
+		return r.typeName(obj, strategy)
+	}
+	name := r.genericName(named)
+	if decl, ok := r.decls[obj]; (ok && decl.annotations["oapi_name"] != "") || obj.Pkg() == nil {
+		return name
+	}
+	switch strategy {
+	case NamingPackageQualified:
+		return obj.Pkg().Name() + "." + name
+	case NamingImportPathHash:
+		// the type arguments are part of the hash, their packages are not
+		// part of the name
+		return name + "_" + importPathHash(types.TypeString(named, nil))
+	}
+
+	return name
+}
+
+// genericName names an instantiated generic type with the GenericNamer, from
+// the bare names of the generic type and of its type arguments.
+func (r *schemaResolver) genericName(named *types.Named) string {
+	args := named.TypeArgs()
+	argNames := make([]string, 0, args.Len())
+	for i := 0; i < args.Len(); i++ {
+		argNames = append(argNames, r.typeArgName(args.At(i)))
+	}
+	namer := r.opts.genericNamer
+	if namer == nil {
+		namer = DefaultGenericNamer
+	}
+
+	return namer(r.typeName(named.Obj(), NamingBare), argNames)
+}
+
+// typeName returns the name of the type declared by obj according to
+// strategy, or the name it is given with oapi_name.
+func (r *schemaResolver) typeName(obj *types.TypeName, strategy NamingStrategy) string {
+	if decl, ok := r.decls[obj]; ok && decl.annotations["oapi_name"] != "" {
+		return decl.annotations["oapi_name"]
+	}
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	switch strategy {
+	case NamingPackageQualified:
+		return obj.Pkg().Name() + "." + obj.Name()
+	case NamingImportPathHash:
+		return obj.Name() + "_" + importPathHash(obj.Pkg().Path())
+	}
+
+	return obj.Name()
+}
+
+func importPathHash(path string) string {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// bareName returns the name of named without qualification, its oapi_name
+// or type name, composed with its type arguments by the GenericNamer.
+func (r *schemaResolver) bareName(named *types.Named) string {
+	if named.TypeArgs().Len() > 0 {
+		return r.genericName(named)
+	}
+	return r.typeName(named.Obj(), NamingBare)
+}
+
+// typeArgName returns the bare name used for typ when it is the type argument
+// of an instantiated generic type.
+func (r *schemaResolver) typeArgName(typ types.Type) string {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Named:
+		return r.bareName(t)
+	case *types.Basic:
+		return capitalize(t.Name())
+	case *types.Pointer:
+		return r.typeArgName(t.Elem())
+	case *types.Slice:
+		return r.typeArgName(t.Elem()) + "List"
+	case *types.Array:
+		return r.typeArgName(t.Elem()) + "List"
+	case *types.Map:
+		return r.typeArgName(t.Key()) + r.typeArgName(t.Elem()) + "Map"
+	}
+
+	return "Object"
+}
+
+// disambiguate renames the components generated in NamingAuto mode. Each
+// component is named after its type unless another component has the same
+// name, in which case both are qualified with their package name and, if
+// that is still ambiguous, keep the hash of their import path. Every $ref is
+// updated accordingly.
+func (r *schemaResolver) disambiguate() {
+	pending := make([]string, 0, len(r.schemas))
+	for name := range r.schemas {
+		if _, ok := r.components[name]; ok {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+
+	names := map[string]string{}
+	taken := map[string]bool{}
+	for _, strategy := range []NamingStrategy{NamingBare, NamingPackageQualified} {
+		count := map[string]int{}
+		for _, key := range pending {
+			count[r.componentNameWith(r.components[key], strategy)]++
+		}
+		next := []string{}
+		for _, key := range pending {
+			name := r.componentNameWith(r.components[key], strategy)
+			if count[name] > 1 || taken[name] {
+				next = append(next, key)
+				continue
+			}
+			names[key] = name
+			taken[name] = true
+		}
+		pending = next
+	}
+
+	refs := map[string]string{}
+	schemas := openapi3.Schemas{}
+	for key, schema := range r.schemas {
+		name := r.disambiguatedName(key, names)
+		refs[createRef(key)] = createRef(name)
+		schemas[name] = schema
+	}
+	visited := map[*openapi3.Schema]bool{}
+	for _, schema := range schemas {
+		renameRefs(schema, refs, visited)
+	}
+	r.schemas = schemas
+	components := map[string]*types.Named{}
+	for key, named := range r.components {
+		components[r.disambiguatedName(key, names)] = named
+	}
+	r.components = components
+}
+
+// componentNames returns the names of the generated components by the type
+// they are generated for, written like types.TypeString so the types of
+// operations, known by reflect, can be matched.
+func (r *schemaResolver) componentNames() map[string]string {
+	names := map[string]string{}
+	for name, named := range r.components {
+		if _, ok := r.schemas[name]; ok {
+			names[types.TypeString(named, nil)] = name
+		}
+	}
+	return names
+}
+
+// disambiguatedName returns the name of the component key given the names of
+// the components of named types, hoisted anonymous structs are named after
+// the renamed parent.
+func (r *schemaResolver) disambiguatedName(key string, names map[string]string) string {
+	if name, ok := names[key]; ok {
+		return name
+	}
+	if hoisted, ok := r.hoisted[key]; ok {
+		return r.disambiguatedName(hoisted.parent, names) + hoisted.field
+	}
+	return key
+}
+
+// renameRefs replaces the $refs found in ref and the schemas below it
+// according to refs.
+func renameRefs(ref *openapi3.SchemaRef, refs map[string]string, visited map[*openapi3.Schema]bool) {
+	if ref == nil {
+		return
+	}
+	if renamed, ok := refs[ref.Ref]; ok {
+		ref.Ref = renamed
+	}
+	schema := ref.Value
+	if schema == nil || visited[schema] {
+		return
+	}
+	visited[schema] = true
+
+	for _, property := range schema.Properties {
+		renameRefs(property, refs, visited)
+	}
+	for _, refList := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, s := range refList {
+			renameRefs(s, refs, visited)
+		}
+	}
+	renameRefs(schema.Items, refs, visited)
+	renameRefs(schema.Not, refs, visited)
+	renameRefs(schema.AdditionalProperties.Schema, refs, visited)
+	if mappings, ok := schema.Extensions["x-oneOf-mappings"].(map[string]string); ok {
+		renamed := map[string]string{}
+		for ref, mapping := range mappings {
+			if to, ok := refs[ref]; ok {
+				ref = to
+			}
+			renamed[ref] = mapping
+		}
+		schema.Extensions["x-oneOf-mappings"] = renamed
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
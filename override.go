@@ -0,0 +1,183 @@
+package openapi3Struct
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// schemaMethodName is the method types implement to provide their own
+	// schema, with the signature OpenAPISchema() *openapi3.Schema
+	schemaMethodName = "OpenAPISchema"
+	openapi3Path     = "github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaMethodCall is a component whose schema is returned by the
+// OpenAPISchema method of its type, called once every schema is resolved.
+type schemaMethodCall struct {
+	name  string
+	named *types.Named
+	decl  *typeDecl
+}
+
+// overriddenSchema returns the schema a type provides itself, either as the
+// literal JSON schema of its oapi_schema_json annotation, or through its
+// OpenAPISchema method. Methods are called later by callSchemaMethods, in the
+// meantime the component holds an empty schema. The returned schema is taken
+// verbatim, without the description of the type.
+func (r *schemaResolver) overriddenSchema(decl *typeDecl, named *types.Named, name string) (*openapi3.Schema, bool) {
+	if schemaJSON := decl.annotations["oapi_schema_json"]; schemaJSON != "" {
+		schema := &openapi3.Schema{}
+		if err := schema.UnmarshalJSON([]byte(schemaJSON)); err != nil {
+			r.report(decl.pkg, decl.obj.Pos(), SeverityError, "invalid value of oapi_schema_json of %s: %v", named, err)
+			return nil, false
+		}
+		return schema, true
+	}
+	if !hasSchemaMethod(named) {
+		return nil, false
+	}
+	if reason := r.uncallableSchemaMethod(decl, named); reason != "" {
+		r.report(decl.pkg, decl.obj.Pos(), SeverityWarning, "%s.%s can not be called because %s, annotate the type with oapi_schema_json instead", named, schemaMethodName, reason)
+		return nil, false
+	}
+	r.schemaMethods = append(r.schemaMethods, schemaMethodCall{name: name, named: named, decl: decl})
+
+	return &openapi3.Schema{}, true
+}
+
+// hasSchemaMethod reports whether named, or a pointer to it, has the method
+// OpenAPISchema() *openapi3.Schema.
+func hasSchemaMethod(named *types.Named) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), false, named.Obj().Pkg(), schemaMethodName)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	ptr, ok := types.Unalias(sig.Results().At(0).Type()).(*types.Pointer)
+	if !ok {
+		return false
+	}
+	result, ok := types.Unalias(ptr.Elem()).(*types.Named)
+	if !ok || result.Obj().Pkg() == nil {
+		return false
+	}
+
+	return result.Obj().Pkg().Path() == openapi3Path && result.Obj().Name() == "Schema"
+}
+
+// uncallableSchemaMethod returns why the OpenAPISchema method of named can not
+// be called from a generated program, or "" when it can.
+func (r *schemaResolver) uncallableSchemaMethod(decl *typeDecl, named *types.Named) string {
+	switch {
+	case r.moduleDir == "":
+		return "the parsed packages do not belong to a module"
+	case decl.pkg.Name == "main":
+		return "its package can not be imported"
+	case !named.Obj().Exported():
+		return "the type is not exported"
+	case named.TypeArgs().Len() > 0:
+		return "the type is generic"
+	}
+	return ""
+}
+
+// callSchemaMethods fills the components of the types implementing
+// OpenAPISchema with the schemas their method returns. The methods are called
+// by a program generated in the module of the parsed packages, which prints
+// the schemas as JSON.
+func (r *schemaResolver) callSchemaMethods() {
+	if len(r.schemaMethods) == 0 {
+		return
+	}
+	schemas, err := r.runSchemaProgram()
+	if err != nil {
+		for _, call := range r.schemaMethods {
+			r.report(call.decl.pkg, call.decl.obj.Pos(), SeverityError, "calling %s.%s: %v", call.named, schemaMethodName, err)
+		}
+		return
+	}
+	for _, call := range r.schemaMethods {
+		schema := schemas[call.name]
+		if schema == nil {
+			r.report(call.decl.pkg, call.decl.obj.Pos(), SeverityError, "%s.%s returned a nil schema", call.named, schemaMethodName)
+			continue
+		}
+		r.schemas[call.name].Value = schema
+	}
+}
+
+// runSchemaProgram generates, runs and removes the program calling the
+// OpenAPISchema methods, and returns the schemas it printed by component name.
+func (r *schemaResolver) runSchemaProgram() (map[string]*openapi3.Schema, error) {
+	dir, err := os.MkdirTemp(r.moduleDir, ".openapi3struct-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), r.schemaProgram(), 0o644); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	schemas := map[string]*openapi3.Schema{}
+	if err := json.Unmarshal(stdout.Bytes(), &schemas); err != nil {
+		return nil, fmt.Errorf("decoding schemas: %w", err)
+	}
+
+	return schemas, nil
+}
+
+// schemaProgram returns the source of the program calling the OpenAPISchema
+// methods, on a pointer to the zero value so value and pointer receivers work
+// alike.
+func (r *schemaResolver) schemaProgram() []byte {
+	aliases := map[string]string{}
+	for _, call := range r.schemaMethods {
+		path := call.named.Obj().Pkg().Path()
+		if _, ok := aliases[path]; !ok {
+			aliases[path] = fmt.Sprintf("p%d", len(aliases))
+		}
+	}
+	paths := make([]string, 0, len(aliases))
+	for path := range aliases {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var src bytes.Buffer
+	src.WriteString("package main\n\nimport (\n\t\"encoding/json\"\n\t\"os\"\n\n")
+	fmt.Fprintf(&src, "\t%q\n", openapi3Path)
+	for _, path := range paths {
+		fmt.Fprintf(&src, "\t%s %q\n", aliases[path], path)
+	}
+	src.WriteString(")\n\nfunc main() {\n\tschemas := map[string]*openapi3.Schema{\n")
+	for _, call := range r.schemaMethods {
+		obj := call.named.Obj()
+		fmt.Fprintf(&src, "\t\t%s: new(%s.%s).%s(),\n", strconv.Quote(call.name), aliases[obj.Pkg().Path()], obj.Name(), schemaMethodName)
+	}
+	src.WriteString("\t}\n\tif err := json.NewEncoder(os.Stdout).Encode(schemas); err != nil {\n\t\tpanic(err)\n\t}\n}\n")
+
+	return src.Bytes()
+}
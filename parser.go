@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"go/ast"
+	"go/types"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -23,13 +24,25 @@ type Parser struct {
 	T           openapi3.T
 	packagePath []string
 	paths       []domain.Path
+	schemaOpts  schemaOptions
+	strict      bool
+	diagnostics Diagnostics
+	// operationIDs generates the operationId of operations without one
+	operationIDs domain.OperationIDStrategy
+	// typeRefs are the $refs of the added operations to Go types
+	typeRefs []domain.TypeRef
+	// componentNames are the names of the generated components by type
+	componentNames map[string]string
+	// packageNames are the names of the loaded packages by import path
+	packageNames map[string]string
 }
 
 type Option func(p Parser) Parser
 
 func NewParser(t openapi3.T, options ...Option) *Parser {
 	p := Parser{
-		T: t,
+		T:            t,
+		operationIDs: domain.DefaultOperationID,
 	}
 	for _, option := range options {
 		p = option(p)
@@ -45,36 +58,197 @@ func WithPackagePaths(paths []string) Option {
 	}
 }
 
-func (p *Parser) AddPath(epDoc domain.EndpointDoc) {
+// WithTypeMapping makes every field of the given type, written as
+// "import/path.Type" (e.g. "github.com/shopspring/decimal.Decimal"), use
+// schema instead of the schema generated from its declaration. It takes
+// precedence over the built-in mappings of well known types like time.Time.
+func WithTypeMapping(typeName string, schema *openapi3.Schema) Option {
+	return func(p Parser) Parser {
+		mappings := map[string]*openapi3.Schema{}
+		for k, v := range p.schemaOpts.typeMappings {
+			mappings[k] = v
+		}
+		mappings[typeName] = schema
+		p.schemaOpts.typeMappings = mappings
+		return p
+	}
+}
+
+// WithGenericNamer sets how the components of instantiated generic types are
+// named, DefaultGenericNamer is used otherwise.
+func WithGenericNamer(namer GenericNamer) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.genericNamer = namer
+		return p
+	}
+}
+
+// WithRequiredStrategy sets which struct fields are required and which are
+// nullable, RequiredByType is used otherwise.
+func WithRequiredStrategy(strategy RequiredStrategy) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.requiredStrategy = strategy
+		return p
+	}
+}
+
+// WithDocTitles sets the title of schemas and properties to the first
+// sentence of the doc comment their description is taken from.
+func WithDocTitles(enabled bool) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.docTitles = enabled
+		return p
+	}
+}
+
+// WithNamingStrategy sets how the components of the generated schemas are
+// named, NamingBare is used otherwise.
+func WithNamingStrategy(strategy NamingStrategy) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.namingStrategy = strategy
+		return p
+	}
+}
+
+// WithHoistedStructs generates components for the anonymous structs of struct
+// fields instead of inlining them. Components are named after the parent
+// and the field, e.g. OrderMeta for the Meta field of Order.
+func WithHoistedStructs(enabled bool) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.hoistStructs = enabled
+		return p
+	}
+}
+
+// WithEmbeddedFlattening promotes the fields of embedded structs into the
+// properties of the embedding struct, following the shadowing rules of
+// encoding/json, instead of composing the embedded schemas with allOf.
+// Embedded structs annotated with oapi_allOf are still composed.
+func WithEmbeddedFlattening(enabled bool) Option {
+	return func(p Parser) Parser {
+		p.schemaOpts.flattenEmbedded = enabled
+		return p
+	}
+}
+
+// WithKeyTransformer registers a discriminator mapping key transformer, which
+// oapi_discriminator_mapped_parser can refer to by name, e.g.
+// oapi_discriminator_mapped_parser:"trimSuffix=Event,myTransformer". It takes
+// precedence over a built-in transformer of the same name.
+func WithKeyTransformer(name string, transformer KeyTransformer) Option {
+	return func(p Parser) Parser {
+		transformers := map[string]KeyTransformer{}
+		for k, v := range p.schemaOpts.keyTransformers {
+			transformers[k] = v
+		}
+		transformers[name] = transformer
+		p.schemaOpts.keyTransformers = transformers
+		return p
+	}
+}
+
+// WithStrictMode makes ParseSchemasFromStructs fail on warnings as well as on
+// errors, e.g. for fields of types which can not be encoded as JSON.
+func WithStrictMode(enabled bool) Option {
+	return func(p Parser) Parser {
+		p.strict = enabled
+		return p
+	}
+}
+
+// WithOperationIDStrategy sets how the operationId of operations which do not
+// set one is generated, domain.DefaultOperationID is used otherwise. A nil
+// strategy leaves the operationId out.
+func WithOperationIDStrategy(strategy domain.OperationIDStrategy) Option {
+	return func(p Parser) Parser {
+		p.operationIDs = strategy
+		return p
+	}
+}
+
+// AddPath adds the operation of epDoc to its path, next to the operations
+// already added for other methods. Adding a second operation for the same
+// method and path is an error.
+func (p *Parser) AddPath(epDoc domain.EndpointDoc) error {
+	if !domain.IsMethod(epDoc.Method) {
+		return fmt.Errorf("unknown request method %q for path %s", epDoc.Method, epDoc.GetPath())
+	}
 	path := epDoc.BuildOpenAPiStruct()
+	for method, op := range path.Item.Operations() {
+		if op.OperationID == "" && p.operationIDs != nil {
+			op.OperationID = p.operationIDs(method, path.Path)
+		}
+	}
 	if p.T.Paths == nil {
 		p.T.Paths = &openapi3.Paths{}
 	}
-	// TODO improve this to add checks for all kinds of optional fields
 	storedPath := p.T.Paths.Value(path.Path)
 
 	if storedPath == nil {
 		p.T.Paths.Set(path.Path, &path.Item)
-		return
+	} else {
+		for method, op := range path.Item.Operations() {
+			if storedPath.GetOperation(method) != nil {
+				return fmt.Errorf("duplicate %s operation on path %s", method, path.Path)
+			}
+			storedPath.SetOperation(method, op)
+		}
 	}
+	refs := epDoc.PathItem.TypeRefs()
+	p.typeRefs = append(p.typeRefs, refs...)
+	p.renameTypeRefs(refs)
 
-	if path.Item.Delete != nil {
-		storedPath.Delete = path.Item.Delete
-	}
-	if path.Item.Head != nil {
-		storedPath.Head = path.Item.Head
-	}
-	if path.Item.Post != nil {
-		storedPath.Post = path.Item.Post
-	}
-	if path.Item.Get != nil {
-		storedPath.Get = path.Item.Get
-	}
-	if path.Item.Put != nil {
-		storedPath.Put = path.Item.Put
-	}
+	return nil
+}
 
-	p.T.Paths.Set(path.Path, storedPath)
+// renameTypeRefs points the schemas of operations for Go types at the schemas
+// generated for them: the schema of a type mapping, custom before built-in,
+// the component generated by ParseSchemasFromStructs, which honors oapi_name
+// and the generic namer, the inline schema of basic, slice and map types
+// without a component, or else the name the naming strategy gives the type.
+// Refs of operations added before the schemas are generated are renamed again
+// afterwards.
+func (p *Parser) renameTypeRefs(refs []domain.TypeRef) {
+	for _, ref := range refs {
+		key := ref.Type.PkgPath() + "." + ref.Type.Name()
+		if schema, ok := p.schemaOpts.typeMappings[key]; ok {
+			copied := *schema
+			*ref.Schema = openapi3.SchemaRef{Value: &copied}
+			continue
+		}
+		if schema, ok := domain.TypeMapping(key); ok {
+			*ref.Schema = openapi3.SchemaRef{Value: schema}
+			continue
+		}
+		if name, ok := p.componentNames[key]; ok {
+			*ref.Schema = openapi3.SchemaRef{Ref: createRef(name)}
+			continue
+		}
+		if ref.Inline != nil {
+			*ref.Schema = *ref.Inline
+			continue
+		}
+		// generic types which are not generated keep the default name
+		if strings.Contains(ref.Type.Name(), "[") {
+			continue
+		}
+		name := ref.Type.Name()
+		switch p.schemaOpts.namingStrategy {
+		case NamingPackageQualified:
+			pkgName, ok := p.packageNames[ref.Type.PkgPath()]
+			if !ok {
+				// the package is not loaded yet, or not at all
+				if p.componentNames != nil {
+					fmt.Printf("Warning: no component is generated for %s, its package is not loaded\n", key)
+				}
+				continue
+			}
+			name = pkgName + "." + name
+		case NamingImportPathHash:
+			name += "_" + importPathHash(ref.Type.PkgPath())
+		}
+		*ref.Schema = openapi3.SchemaRef{Ref: createRef(name)}
+	}
 }
 
 func (p *Parser) SaveYamlToFile(path string) error {
@@ -116,82 +290,85 @@ func (p *Parser) Validate(ctx context.Context) error {
 	return nil
 }
 
+// Diagnostics returns the errors and warnings found by the last call to
+// ParseSchemasFromStructs, including the warnings which did not fail it.
+func (p *Parser) Diagnostics() Diagnostics {
+	return p.diagnostics
+}
+
+// ParseSchemasFromStructs generates the component schemas of the annotated
+// types in the package paths. Problems found in the source are returned as
+// Diagnostics, warnings only fail the generation in strict mode.
 func (p *Parser) ParseSchemasFromStructs() error {
-	cfg := &packages.Config{Mode: packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes}
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps | packages.NeedModule}
 	pkgs, err := packages.Load(cfg, p.packagePath...)
 	if err != nil {
 		return err
 	}
-	if packages.PrintErrors(pkgs) > 0 {
-		return err
+	p.diagnostics = nil
+	p.packageNames = map[string]string{}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		p.packageNames[pkg.PkgPath] = pkg.Name
+		for _, e := range pkg.Errors {
+			p.diagnostics = append(p.diagnostics, packageDiagnostic(e))
+		}
+	})
+	if len(p.diagnostics) > 0 {
+		return p.diagnostics
 	}
 	if p.T.Components.Schemas == nil {
 		p.T.Components.Schemas = openapi3.Schemas{}
 	}
 
-	schemas := walkPackageAndResolveSchemas(pkgs)
-	for name, schema := range schemas {
+	opts := p.schemaOpts
+	opts.openapi31 = strings.HasPrefix(p.T.OpenAPI, "3.1")
+	r := resolvePackages(pkgs, opts)
+	schemas, diagnostics := r.schemas, r.diagnostics
+	p.diagnostics = diagnostics
+	if diagnostics.HasErrors() || (p.strict && len(diagnostics) > 0) {
+		return diagnostics
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
 		if _, ok := p.T.Components.Schemas[name]; ok {
 			return fmt.Errorf("Generated schema conflict Name=%s", name)
 		}
 
-		p.T.Components.Schemas[name] = schema
+		p.T.Components.Schemas[name] = schemas[name]
 	}
+	p.componentNames = r.componentNames()
+	p.renameTypeRefs(p.typeRefs)
 
 	return nil
 }
 
-func walkPackageAndResolveSchemas(pkgs []*packages.Package) openapi3.Schemas {
-	schemas := openapi3.Schemas{}
-	declarationMap := map[string]*ast.TypeSpec{}
-	//this loop is to collect all the type declarations, this way when we parse star expressions we can resolve them as if they were the actual type
-	for _, pkg := range pkgs {
-		for _, f := range pkg.Syntax {
-			for _, v := range f.Decls {
-				switch decl := v.(type) {
-				case *ast.GenDecl:
-					if !strings.Contains(decl.Doc.Text(), openapiSchemaDecoration) && !strings.Contains(decl.Doc.Text(), swaggerSchemaDecoration) {
-						continue
-					}
-					for _, s := range decl.Specs {
-						switch spec := s.(type) {
-						case *ast.TypeSpec:
-							declarationMap[spec.Name.Name] = spec
-						}
-					}
-				}
-			}
+func walkPackageAndResolveSchemas(pkgs []*packages.Package, opts schemaOptions) (openapi3.Schemas, Diagnostics) {
+	r := resolvePackages(pkgs, opts)
+	return r.schemas, r.diagnostics
+}
+
+// resolvePackages resolves the schemas of the annotated types of pkgs and of
+// the types they refer to.
+func resolvePackages(pkgs []*packages.Package, opts schemaOptions) *schemaResolver {
+	r := newSchemaResolver(pkgs, opts)
+	for _, decl := range r.annotated {
+		if named, ok := decl.obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			// generic declarations are generated for each instantiation
+			continue
 		}
+		r.component(decl, decl.obj.Type())
 	}
-	for _, pkg := range pkgs {
-		for _, f := range pkg.Syntax {
-			for _, v := range f.Decls {
-				switch decl := v.(type) {
-				case *ast.FuncDecl:
-					break
-				case *ast.GenDecl:
-					if !strings.Contains(decl.Doc.Text(), openapiSchemaDecoration) && !strings.Contains(decl.Doc.Text(), swaggerSchemaDecoration) {
-						continue
-					}
-					for _, s := range decl.Specs {
-						doc := ""
-						if decl.Doc != nil {
-							doc = decl.Doc.Text()
-						}
-						// TODO: add schema renaming
-						name, schema := resolveSchema(schemas, s, doc, declarationMap)
-						if name != nil {
-							schemas[*name] = openapi3.NewSchemaRef("", &schema)
-						}
-					}
-					break
-				case *ast.BadDecl:
-					break
-				default:
-					break
-				}
-			}
-		}
+	for _, inst := range r.instances {
+		r.resolveType(inst)
+	}
+	r.callSchemaMethods()
+	if opts.namingStrategy == NamingAuto {
+		r.disambiguate()
 	}
-	return schemas
+	r.mapDiscriminators()
+	return r
 }
@@ -0,0 +1,588 @@
+package openapi3Struct
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/nextap-solutions/openapi3Struct/domain"
+	"github.com/nextap-solutions/openapi3Struct/testdata/crosspkg/api"
+	"github.com/nextap-solutions/openapi3Struct/testdata/crosspkg/billing"
+	"github.com/nextap-solutions/openapi3Struct/testdata/recursive"
+	versioned "github.com/nextap-solutions/openapi3Struct/testdata/versioned/v2"
+)
+
+// parseTestdata runs ParseSchemasFromStructs on packages below testdata.
+func parseTestdata(t *testing.T, paths ...string) *Parser {
+	t.Helper()
+	p := NewParser(openapi3.T{Components: &openapi3.Components{}}, WithPackagePaths(paths))
+	if err := p.ParseSchemasFromStructs(); err != nil {
+		t.Fatalf("parse schemas: %v", err)
+	}
+	return p
+}
+
+func TestParseSchemasFromStructs_CrossPackageReferences(t *testing.T) {
+	t.Parallel()
+
+	p := parseTestdata(t, "./testdata/crosspkg/api")
+	schemas := p.T.Components.Schemas
+
+	customer, ok := schemas["Customer"]
+	if !ok {
+		t.Fatal("expected Customer schema")
+	}
+	if ref := customer.Value.Properties["address"].Ref; ref != "#/components/schemas/Address" {
+		t.Fatalf("expected address to be $ref to Address, got %q", ref)
+	}
+	if ref := customer.Value.Properties["invoices"].Value.Items.Ref; ref != "#/components/schemas/Invoice" {
+		t.Fatalf("expected invoices items to be $ref to Invoice, got %q", ref)
+	}
+
+	address, ok := schemas["Address"]
+	if !ok {
+		t.Fatal("expected Address from another package to be generated")
+	}
+	if _, ok := address.Value.Properties["street"]; !ok {
+		t.Fatalf("expected Address property 'street', got %v", address.Value.Properties)
+	}
+	invoice, ok := schemas["Invoice"]
+	if !ok {
+		t.Fatal("expected Invoice from another package to be generated")
+	}
+	if ref := invoice.Value.Properties["address"].Ref; ref != "#/components/schemas/Address" {
+		t.Fatalf("expected Invoice address to be $ref to Address, got %q", ref)
+	}
+}
+
+func TestParseSchemasFromStructs_StrictMode(t *testing.T) {
+	t.Parallel()
+
+	p := parseTestdata(t, "./testdata/diagnostics/warning")
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Severity != SeverityWarning {
+		t.Fatalf("expected a single warning for the ack field, got %v", diagnostics)
+	}
+	if pos := diagnostics[0].Pos; !strings.HasSuffix(pos.Filename, "warning.go") || pos.Line != 9 || pos.Column != 2 {
+		t.Fatalf("expected the warning at warning.go:9:2, got %v", pos)
+	}
+	if _, ok := p.T.Components.Schemas["Event"]; !ok {
+		t.Fatal("expected warnings not to fail the generation")
+	}
+
+	strict := NewParser(openapi3.T{Components: &openapi3.Components{}}, WithPackagePaths([]string{"./testdata/diagnostics/warning"}), WithStrictMode(true))
+	err := strict.ParseSchemasFromStructs()
+	var errs Diagnostics
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected strict mode to fail with the warning, got %v", err)
+	}
+}
+
+func TestParseSchemasFromStructs_TypeAnnotations(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{Components: &openapi3.Components{}}, WithPackagePaths([]string{"./testdata/diagnostics/annotations"}))
+	err := p.ParseSchemasFromStructs()
+	var errs Diagnostics
+	if !errors.As(err, &errs) || !errs.HasErrors() {
+		t.Fatalf("expected malformed and unknown type annotations to fail, got %v", err)
+	}
+	messages := []string{}
+	for _, d := range errs {
+		messages = append(messages, fmt.Sprintf("annotations.go:%d:%d: %s", d.Pos.Line, d.Pos.Column, d.Message))
+	}
+	expected := []string{
+		`annotations.go:3:4: malformed annotation: invalid value of annotation "oapi_name": missing closing quote`,
+		`annotations.go:8:4: unknown type annotation oapi_nmae`,
+		`annotations.go:15:4: unknown type annotation oapi_discriminator_mapped_parsr`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestParseSchemasFromStructs_PackageErrors(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{Components: &openapi3.Components{}}, WithPackagePaths([]string{"./testdata/diagnostics/broken"}))
+	err := p.ParseSchemasFromStructs()
+	var errs Diagnostics
+	if !errors.As(err, &errs) || !errs.HasErrors() {
+		t.Fatalf("expected package errors to be returned as Diagnostics, got %v", err)
+	}
+	if pos := errs[0].Pos; !strings.HasSuffix(pos.Filename, "broken.go") || pos.Line != 5 {
+		t.Fatalf("expected the error at broken.go:5, got %v", pos)
+	}
+}
+
+func TestParseSchemasFromStructs_RecursiveTypes(t *testing.T) {
+	t.Parallel()
+
+	p := parseTestdata(t, "./testdata/recursive")
+	schemas := p.T.Components.Schemas
+
+	expected := map[string]string{
+		"Tree.root":            "#/components/schemas/Node",
+		"Node.parent":          "#/components/schemas/Tree",
+		"Node.labels":          "#/components/schemas/Attributes",
+		"Edge.from":            "#/components/schemas/Vertex",
+		"CategoryString.value": "",
+	}
+	for path, ref := range expected {
+		name, property, _ := strings.Cut(path, ".")
+		schema, ok := schemas[name]
+		if !ok {
+			t.Fatalf("expected %s schema, got %v", name, schemas)
+		}
+		if got := schema.Value.Properties[property].Ref; got != ref {
+			t.Errorf("expected %s to be $ref %q, got %q", path, ref, got)
+		}
+	}
+	items := map[string]string{
+		"Node.children":           "#/components/schemas/Node",
+		"Vertex.edges":            "#/components/schemas/Edge",
+		"CategoryString.children": "#/components/schemas/CategoryString",
+	}
+	for path, ref := range items {
+		name, property, _ := strings.Cut(path, ".")
+		if got := schemas[name].Value.Properties[property].Value.Items.Ref; got != ref {
+			t.Errorf("expected %s items to be $ref %q, got %q", path, ref, got)
+		}
+	}
+	if got := schemas["Attributes"].Value.AdditionalProperties.Schema.Ref; got != "#/components/schemas/Attributes" {
+		t.Errorf("expected Attributes values to be $ref to itself, got %q", got)
+	}
+	if _, ok := schemas["NodeRef"]; ok {
+		t.Error("expected named pointer types to be inlined")
+	}
+
+	p.T.OpenAPI = "3.0.3"
+	p.T.Info = &openapi3.Info{Title: "recursive", Version: "1.0.0"}
+	p.T.Paths = openapi3.NewPaths()
+	if err := p.Validate(context.Background()); err != nil {
+		t.Fatalf("expected a valid document, got %v", err)
+	}
+
+	again := parseTestdata(t, "./testdata/recursive")
+	first, _ := json.Marshal(schemas)
+	second, _ := json.Marshal(again.T.Components.Schemas)
+	if string(first) != string(second) {
+		t.Error("expected the generated components to be stable")
+	}
+}
+
+func TestParseSchemasFromStructs_SchemaOverrides(t *testing.T) {
+	t.Parallel()
+
+	p := parseTestdata(t, "./testdata/override")
+	schemas := p.T.Components.Schemas
+
+	expected := map[string]string{
+		"Timestamp": `{"format":"int64","minimum":0,"type":"integer"}`,
+		"Duration":  `{"pattern":"^([0-9]+(ns|us|ms|s|m|h))+$","type":"string"}`,
+		"Priority":  `{"enum":["low","high"],"type":"string"}`,
+	}
+	for name, schema := range expected {
+		ref, ok := schemas[name]
+		if !ok {
+			t.Fatalf("expected %s schema, got %v", name, schemas)
+		}
+		data, err := json.Marshal(ref.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != schema {
+			t.Errorf("expected %s schema to be taken verbatim as %s, got %s", name, schema, data)
+		}
+	}
+	if ref := schemas["Event"].Value.Properties["at"].Ref; ref != "#/components/schemas/Timestamp" {
+		t.Errorf("expected at to be $ref to Timestamp, got %q", ref)
+	}
+	if len(p.Diagnostics()) != 0 {
+		t.Errorf("unexpected diagnostics %v", p.Diagnostics())
+	}
+}
+
+func TestAddPath(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{})
+	methods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE", "CONNECT"}
+	for _, method := range methods {
+		err := p.AddPath(domain.EndpointDoc{
+			Path:     "orders",
+			Version:  1,
+			Method:   method,
+			PathItem: domain.NewOperationBuilder().WithDescription(method),
+		})
+		if err != nil {
+			t.Fatalf("add %s operation: %v", method, err)
+		}
+	}
+
+	item := p.T.Paths.Value("/v1/orders")
+	if item == nil {
+		t.Fatalf("expected path /v1/orders, got %v", p.T.Paths.InMatchingOrder())
+	}
+	for _, method := range methods {
+		op := item.GetOperation(method)
+		if op == nil || op.Description != method {
+			t.Errorf("expected the %s operation to be kept, got %v", method, op)
+		}
+	}
+
+	err := p.AddPath(domain.EndpointDoc{Path: "orders", Version: 1, Method: "patch", PathItem: domain.NewOperationBuilder()})
+	if err == nil || err.Error() != "duplicate PATCH operation on path /v1/orders" {
+		t.Errorf("expected a duplicate operation error, got %v", err)
+	}
+	if item.Patch.Description != "PATCH" {
+		t.Error("expected the duplicate operation not to overwrite the first one")
+	}
+	err = p.AddPath(domain.EndpointDoc{Path: "orders", Method: "FETCH", PathItem: domain.NewOperationBuilder()})
+	if err == nil || err.Error() != `unknown request method "FETCH" for path /orders` {
+		t.Errorf("expected an unknown method error, got %v", err)
+	}
+}
+
+func TestAddPath_OperationFields(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{})
+	builder := domain.NewOperationBuilder().
+		WithSummary("Get an order").
+		WithDeprecated(true).
+		WithSecurity("oauth", "orders:read").
+		WithSecurity("apiKey").
+		WithServer("https://orders.example.com", "Orders").
+		WithExternalDocs("https://docs.example.com/orders", "Orders guide").
+		WithExtension("x-rate-limit", 100).
+		WithExtension("rate-limit", 100)
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders/{orderId}", Version: 1, Method: "GET", PathItem: builder}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders", Method: "POST", PathItem: domain.NewOperationBuilder().WithOperationID("createOrder").WithoutSecurity()}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(p.T.Paths.Value("/v1/orders/{orderId}").Get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"deprecated":true,"externalDocs":{"description":"Orders guide","url":"https://docs.example.com/orders"},"operationId":"getV1OrdersByOrderId","responses":{"default":{"description":""}},"security":[{"oauth":["orders:read"]},{"apiKey":[]}],"servers":[{"description":"Orders","url":"https://orders.example.com"}],"summary":"Get an order","x-rate-limit":100}`
+	if string(data) != expected {
+		t.Errorf("expected operation\n%s\ngot\n%s", expected, data)
+	}
+	post := p.T.Paths.Value("/orders").Post
+	if post.OperationID != "createOrder" {
+		t.Errorf("expected the given operationId to be kept, got %q", post.OperationID)
+	}
+	if post.Security == nil || len(*post.Security) != 0 {
+		t.Errorf("expected empty security requirements, got %v", post.Security)
+	}
+
+	p = NewParser(openapi3.T{}, WithOperationIDStrategy(nil))
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders", Method: "GET", PathItem: domain.NewOperationBuilder()}); err != nil {
+		t.Fatal(err)
+	}
+	if id := p.T.Paths.Value("/orders").Get.OperationID; id != "" {
+		t.Errorf("expected no operationId without a strategy, got %q", id)
+	}
+}
+
+func TestAddPath_MediaTypes(t *testing.T) {
+	t.Parallel()
+
+	type Order struct{}
+	type Upload struct {
+		Title       string                  `form:"title"`
+		Tags        []string                `json:"tags"`
+		Avatar      *multipart.FileHeader   `form:"avatar"`
+		Attachments []*multipart.FileHeader `form:"attachments"`
+		Data        []byte
+		Stream      io.Reader `form:"-"`
+		secret      string
+	}
+
+	p := NewParser(openapi3.T{})
+	create := domain.NewOperationBuilder().
+		WithRequestBodyType(Order{}, "The order", true).
+		WithRequestBodyContent(domain.ContentTypeXML, Order{}, domain.WithExample("<Order/>")).
+		WithRequestBodyContent(domain.ContentTypeBinary, new(io.Reader)).
+		WithRequestBodyContent(domain.ContentTypeForm, nil).
+		WithResponse(200, "The order", Order{}).
+		WithResponseContent(200, domain.ContentTypeCSV, []byte{}, domain.WithNamedExample("empty", "No orders", "id,total\n"))
+	upload := domain.NewOperationBuilder().WithFileUpload(Upload{}, "The files", true)
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders", Method: "POST", PathItem: create}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPath(domain.EndpointDoc{Path: "uploads", Method: "POST", PathItem: upload}); err != nil {
+		t.Fatal(err)
+	}
+
+	marshal := func(v any) string {
+		t.Helper()
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	post := p.T.Paths.Value("/orders").Post
+	expected := `{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Order"}},"application/octet-stream":{"schema":{"format":"binary","type":"string"}},"application/x-www-form-urlencoded":{},"application/xml":{"example":"\u003cOrder/\u003e","schema":{"$ref":"#/components/schemas/Order"}}},"description":"The order","required":true}`
+	if got := marshal(post.RequestBody.Value); got != expected {
+		t.Errorf("expected request body\n%s\ngot\n%s", expected, got)
+	}
+	expected = `{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Order"}},"text/csv":{"examples":{"empty":{"summary":"No orders","value":"id,total\n"}},"schema":{"format":"binary","type":"string"}}},"description":"The order"}`
+	if got := marshal(post.Responses.Status(200).Value); got != expected {
+		t.Errorf("expected response\n%s\ngot\n%s", expected, got)
+	}
+	expected = `{"content":{"multipart/form-data":{"encoding":{"Data":{"contentType":"application/octet-stream"},"attachments":{"contentType":"application/octet-stream"},"avatar":{"contentType":"application/octet-stream"}},"schema":{"properties":{"Data":{"format":"binary","type":"string"},"attachments":{"items":{"format":"binary","type":"string"},"type":"array"},"avatar":{"format":"binary","type":"string"},"tags":{"items":{"type":"string"},"type":"array"},"title":{"type":"string"}},"type":"object"}}},"description":"The files","required":true}`
+	if got := marshal(p.T.Paths.Value("/uploads").Post.RequestBody.Value); got != expected {
+		t.Errorf("expected file upload body\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestAddPath_ResponseOptions(t *testing.T) {
+	t.Parallel()
+
+	type Order struct {
+		ID    string `json:"id"`
+		Total int    `json:"total,omitempty"`
+	}
+	type Problem struct{}
+
+	p := NewParser(openapi3.T{})
+	builder := domain.NewOperationBuilder().
+		WithResponse(201, "Created", Order{},
+			domain.WithHeader("Location", "URL of the order", "", true),
+			domain.WithHeader("RateLimit-Remaining", "Requests left", 0, false),
+			domain.WithLink("GetOrder", "getOrdersByOrderId", "Fetches the order", map[string]any{"orderId": "$response.body#/id"}),
+			domain.WithResponseExample("created", "A new order", Order{ID: "o-1"}),
+		).
+		WithResponseCode("4XX", "Client error", Problem{}).
+		WithResponseCodeContent("4XX", domain.ContentTypeXML, Problem{}).
+		WithResponseCode("default", "Unexpected error", nil).
+		WithResponseCodeContent("default", domain.ContentTypeCSV, []byte{}).
+		WithResponseCodeContent("6XX", domain.ContentTypeCSV, []byte{}).
+		WithResponseCode("600", "Invalid", nil)
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders", Method: "POST", PathItem: builder}); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := p.T.Paths.Value("/orders").Post.Responses
+	if responses.Len() != 3 || responses.Value("4XX") == nil || responses.Default() == nil {
+		t.Fatalf("expected 201, 4XX and default responses, got %v", responses.Map())
+	}
+	clientError := responses.Value("4XX").Value
+	if clientError.Content.Get(domain.ContentTypeJSON) == nil || clientError.Content.Get(domain.ContentTypeXML).Schema.Ref != "#/components/schemas/Problem" {
+		t.Errorf("expected JSON and XML content on the 4XX response, got %v", clientError.Content)
+	}
+	unexpected := responses.Default().Value
+	if *unexpected.Description != "Unexpected error" || unexpected.Content.Get(domain.ContentTypeCSV) == nil {
+		t.Errorf("expected CSV content on the default response, got %v", unexpected.Content)
+	}
+	data, err := json.Marshal(responses.Status(201).Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"content":{"application/json":{"examples":{"created":{"summary":"A new order","value":{"id":"o-1"}}},"schema":{"$ref":"#/components/schemas/Order"}}},"description":"Created","headers":{"Location":{"description":"URL of the order","required":true,"schema":{"type":"string"}},"RateLimit-Remaining":{"description":"Requests left","schema":{"format":"int64","type":"integer"}}},"links":{"GetOrder":{"description":"Fetches the order","operationId":"getOrdersByOrderId","parameters":{"orderId":"$response.body#/id"}}}}`
+	if string(data) != expected {
+		t.Errorf("expected response\n%s\ngot\n%s", expected, data)
+	}
+}
+
+// testPage, testUser and testStatus are the types of TestAddPath_ReflectedTypes.
+// testStatus, a string type without a component, is described inline.
+type testPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+type testUser struct{}
+
+type testStatus string
+
+func TestAddPath_ReflectedTypes(t *testing.T) {
+	t.Parallel()
+
+	expected := map[string]any{
+		`{"$ref":"#/components/schemas/testUser"}`:                                                                   &testUser{},
+		`{"items":{"$ref":"#/components/schemas/testUser"},"type":"array"}`:                                          []*testUser{},
+		`{"items":{"$ref":"#/components/schemas/testUser"},"maxItems":2,"minItems":2,"type":"array"}`:                [2]testUser{},
+		`{"additionalProperties":{"items":{"$ref":"#/components/schemas/testUser"},"type":"array"},"type":"object"}`: map[string][]testUser{},
+		`{"$ref":"#/components/schemas/testPagetestUser"}`:                                                           testPage[testUser]{},
+		`{"$ref":"#/components/schemas/testPageStringIntMap"}`:                                                       testPage[map[string]int]{},
+		`{"$ref":"#/components/schemas/testPagetestPagetestStatus"}`:                                                 testPage[testPage[testStatus]]{},
+		`{"items":{"type":"string"},"type":"array"}`:                                                                 []testStatus{},
+		`{"type":"string"}`:                   "",
+		`{"format":"int32","type":"integer"}`: int32(0),
+		`{"format":"double","type":"number"}`: 0.0,
+		`{"type":"boolean"}`:                  false,
+	}
+	for schema, typ := range expected {
+		p := NewParser(openapi3.T{})
+		builder := domain.NewOperationBuilder().
+			WithRequestBodyType(typ, "", true).
+			WithResponse(200, "", typ).
+			WithParameter("q", "query", "", false, typ)
+		if err := p.AddPath(domain.EndpointDoc{Path: "items", Method: "PUT", PathItem: builder}); err != nil {
+			t.Fatal(err)
+		}
+		op := p.T.Paths.Value("/items").Put
+		refs := map[string]*openapi3.SchemaRef{
+			"request body": op.RequestBody.Value.Content.Get("application/json").Schema,
+			"response":     op.Responses.Status(200).Value.Content.Get("application/json").Schema,
+			"parameter":    op.Parameters[0].Value.Schema,
+		}
+		for kind, ref := range refs {
+			data, err := json.Marshal(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != schema {
+				t.Errorf("expected %s schema of %T to be %s, got %s", kind, typ, schema, data)
+			}
+		}
+	}
+}
+
+func TestAddPath_TypeRefs(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{
+		OpenAPI:    "3.0.3",
+		Info:       &openapi3.Info{Title: "Orders", Version: "1"},
+		Components: &openapi3.Components{},
+	},
+		WithTypeMapping("net/netip.Prefix", openapi3.NewStringSchema().WithFormat("cidr")),
+		WithTypeMapping("time.Duration", openapi3.NewStringSchema().WithFormat("duration")),
+	)
+	builder := domain.NewOperationBuilder().
+		WithParameter("since", "query", "", false, time.Time{}).
+		WithParameter("limit", "query", "", false, uint16(0)).
+		WithParameter("network", "query", "", false, netip.Prefix{}).
+		WithParameter("timeout", "query", "", false, time.Second).
+		WithParameter("month", "query", "", false, time.January).
+		WithParameter("token", "query", "", false, []byte{}).
+		WithResponse(200, "The raw orders", json.RawMessage{}).
+		WithResponse(202, "The receipt", []byte{}).
+		WithResponseCodeContent("202", domain.ContentTypeBinary, []byte{})
+	if err := p.AddPath(domain.EndpointDoc{Path: "orders", Method: "GET", PathItem: builder}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(context.Background()); err != nil {
+		t.Fatalf("expected a valid document, got %v", err)
+	}
+
+	op := p.T.Paths.Value("/orders").Get
+	expected := map[string]string{
+		"since":   `{"format":"date-time","type":"string"}`,
+		"limit":   `{"format":"int32","minimum":0,"type":"integer"}`,
+		"network": `{"format":"cidr","type":"string"}`,
+		"timeout": `{"format":"duration","type":"string"}`,
+		"month":   `{"format":"int64","type":"integer"}`,
+		"token":   `{"format":"byte","type":"string"}`,
+	}
+	for name, schema := range expected {
+		data, err := json.Marshal(op.Parameters.GetByInAndName("query", name).Schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != schema {
+			t.Errorf("expected %s schema %s, got %s", name, schema, data)
+		}
+	}
+	if ref := op.Responses.Status(200).Value.Content.Get("application/json").Schema; ref.Ref != "" {
+		t.Errorf("expected json.RawMessage to be inline, got $ref %q", ref.Ref)
+	}
+	receipt := op.Responses.Status(202).Value.Content
+	if format := receipt.Get(domain.ContentTypeJSON).Schema.Value.Format; format != "byte" {
+		t.Errorf("expected []byte to be base64 encoded in JSON, got format %q", format)
+	}
+	if format := receipt.Get(domain.ContentTypeBinary).Schema.Value.Format; format != "binary" {
+		t.Errorf("expected []byte to be binary in %s, got format %q", domain.ContentTypeBinary, format)
+	}
+}
+
+func TestAddPath_TypeRefsFollowNaming(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{
+		OpenAPI:    "3.0.3",
+		Info:       &openapi3.Info{Title: "Customers", Version: "1"},
+		Components: &openapi3.Components{},
+	},
+		WithPackagePaths([]string{"./testdata/crosspkg/api", "./testdata/recursive"}),
+		WithNamingStrategy(NamingPackageQualified),
+		WithGenericNamer(func(name string, typeArgs []string) string {
+			return name + "Of" + strings.Join(typeArgs, "And")
+		}),
+	)
+	// operations added before and after the schemas are generated
+	before := domain.NewOperationBuilder().WithResponse(200, "The customer", api.Customer{})
+	if err := p.AddPath(domain.EndpointDoc{Path: "customers", Method: "GET", PathItem: before}); err != nil {
+		t.Fatal(err)
+	}
+	categories := domain.NewOperationBuilder().WithResponse(200, "The categories", recursive.Category[string]{})
+	if err := p.AddPath(domain.EndpointDoc{Path: "categories", Method: "GET", PathItem: categories}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseSchemasFromStructs(); err != nil {
+		t.Fatal(err)
+	}
+	after := domain.NewOperationBuilder().WithResponse(200, "The invoice", &billing.Invoice{})
+	if err := p.AddPath(domain.EndpointDoc{Path: "invoices", Method: "GET", PathItem: after}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(context.Background()); err != nil {
+		t.Fatalf("expected a valid document, got %v", err)
+	}
+
+	refs := map[string]string{
+		"/customers":  "#/components/schemas/api.Customer",
+		"/categories": "#/components/schemas/recursive.CategoryOfString",
+		"/invoices":   "#/components/schemas/billing.Invoice",
+	}
+	for path, expected := range refs {
+		ref := p.T.Paths.Value(path).Get.Responses.Status(200).Value.Content.Get("application/json").Schema.Ref
+		if ref != expected {
+			t.Errorf("expected the response of %s to be $ref to %s, got %q", path, expected, ref)
+		}
+	}
+}
+
+func TestAddPath_TypeRefsPackageNames(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(openapi3.T{Components: &openapi3.Components{}},
+		WithPackagePaths([]string{"./testdata/versioned/v2"}),
+		WithNamingStrategy(NamingPackageQualified),
+	)
+	if err := p.ParseSchemasFromStructs(); err != nil {
+		t.Fatal(err)
+	}
+	builder := domain.NewOperationBuilder().
+		WithResponse(200, "The release", versioned.Release{}).
+		WithResponse(202, "The draft", versioned.Draft{})
+	if err := p.AddPath(domain.EndpointDoc{Path: "releases", Method: "POST", PathItem: builder}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the package of import path .../v2 is named versioned
+	responses := p.T.Paths.Value("/releases").Post.Responses
+	for code, expected := range map[int]string{200: "versioned.Release", 202: "versioned.Draft"} {
+		if ref := responses.Status(code).Value.Content.Get(domain.ContentTypeJSON).Schema.Ref; ref != createRef(expected) {
+			t.Errorf("expected the %d response to be $ref to %s, got %q", code, expected, ref)
+		}
+	}
+	if _, ok := p.T.Components.Schemas["versioned.Release"]; !ok {
+		t.Errorf("expected component versioned.Release, got %v", p.T.Components.Schemas)
+	}
+}
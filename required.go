@@ -0,0 +1,91 @@
+package openapi3Struct
+
+import (
+	"go/types"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// RequiredStrategy decides which struct fields are required and which are
+// nullable in the generated schemas. A field annotated with
+// oapi_required:"true" is required with every strategy, a field validated with
+// github.com/go-playground/validator's validate:"required" with every strategy
+// but RequiredExplicit.
+type RequiredStrategy int
+
+const (
+	// RequiredByType makes pointer, slice and map fields as well as fields
+	// tagged omitempty or omitzero optional, every other field is required.
+	// Fields are never nullable. This is the default strategy.
+	RequiredByType RequiredStrategy = iota
+	// RequiredPointerNullable makes every field required, pointer fields are
+	// nullable.
+	RequiredPointerNullable
+	// RequiredExplicit only makes fields annotated with oapi_required:"true"
+	// required. Pointer fields which are not tagged omitempty or omitzero are
+	// nullable.
+	RequiredExplicit
+	// RequiredOmitEmpty makes every field which is not tagged omitempty or
+	// omitzero required. Pointer fields which are not tagged omitempty or
+	// omitzero are nullable.
+	RequiredOmitEmpty
+	// RequiredValidator only makes fields validated with validate:"required"
+	// required. Pointer fields which are not tagged omitempty or omitzero are
+	// nullable.
+	RequiredValidator
+)
+
+// fieldRequirement decides, according to the required strategy, whether a
+// field of type typ is required and whether it is nullable. byType tells if
+// the field is required according to its type alone and explicit if it is
+// annotated with oapi_required:"true".
+func (r *schemaResolver) fieldRequirement(typ types.Type, tag string, json jsonTag, byType bool, explicit bool) (required bool, nullable bool) {
+	_, pointer := types.Unalias(typ).(*types.Pointer)
+	nullable = pointer && !json.omitEmpty
+	validated := hasValidateRequired(tag)
+
+	switch r.opts.requiredStrategy {
+	case RequiredPointerNullable:
+		return true, pointer
+	case RequiredExplicit:
+		return explicit, nullable
+	case RequiredOmitEmpty:
+		return explicit || validated || !json.omitEmpty, nullable
+	case RequiredValidator:
+		return explicit || validated, nullable
+	default:
+		return explicit || validated || (byType && !json.omitEmpty), false
+	}
+}
+
+// nullableSchema returns a copy of schema which also accepts null, using
+// nullable for OpenAPI 3.0 and a null type for OpenAPI 3.1. References are
+// wrapped since sibling keywords of $ref are ignored.
+func (r *schemaResolver) nullableSchema(schema *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if schema.Ref != "" || schema.Value == nil {
+		if r.opts.openapi31 {
+			return openapi3.NewSchemaRef("", &openapi3.Schema{
+				AnyOf: openapi3.SchemaRefs{schema, openapi3.NewSchemaRef("", &openapi3.Schema{
+					Type: &openapi3.Types{openapi3.TypeNull},
+				})},
+			})
+		}
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Nullable: true,
+			AllOf:    openapi3.SchemaRefs{schema},
+		})
+	}
+
+	nullable := *schema.Value
+	if r.opts.openapi31 {
+		if nullable.Type != nil && !nullable.Type.Includes(openapi3.TypeNull) {
+			withNull := append(openapi3.Types{}, *nullable.Type...)
+			withNull = append(withNull, openapi3.TypeNull)
+			nullable.Type = &withNull
+		}
+	} else {
+		nullable.Nullable = true
+	}
+
+	return openapi3.NewSchemaRef("", &nullable)
+}
@@ -0,0 +1,539 @@
+package openapi3Struct
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
+)
+
+// schemaOptions configures how schemas are generated from Go types.
+type schemaOptions struct {
+	// typeMappings overrides the schema of types keyed by "import/path.Type"
+	typeMappings map[string]*openapi3.Schema
+	// genericNamer names the components of instantiated generic types
+	genericNamer GenericNamer
+	// requiredStrategy decides which fields are required and nullable
+	requiredStrategy RequiredStrategy
+	// openapi31 is set when generating an OpenAPI 3.1 document
+	openapi31 bool
+	// docTitles uses the first sentence of descriptions as title
+	docTitles bool
+	// namingStrategy decides the names of the components
+	namingStrategy NamingStrategy
+	// hoistStructs generates components for anonymous structs
+	hoistStructs bool
+	// keyTransformers are the custom discriminator mapping key transformers
+	keyTransformers map[string]KeyTransformer
+	// flattenEmbedded promotes the fields of embedded structs like
+	// encoding/json instead of composing them with allOf
+	flattenEmbedded bool
+}
+
+// typeDecl is a named type declared in the syntax of a loaded package.
+type typeDecl struct {
+	obj  *types.TypeName
+	spec *ast.TypeSpec
+	// description is the documentation of the type without annotations
+	description string
+	// annotations are the annotations of the doc comment by key, like the
+	// component name given with oapi_name
+	annotations map[string]string
+	pkg         *packages.Package
+	annotated   bool
+}
+
+// structSyntax is the syntax of a struct type and the package declaring it.
+type structSyntax struct {
+	node *ast.StructType
+	pkg  *packages.Package
+}
+
+// fieldContext is the struct field whose type is being resolved, anonymous
+// structs are hoisted into components named parent + field.
+type fieldContext struct {
+	parent string
+	field  string
+}
+
+// hoistedStruct is an anonymous struct generated as a component.
+type hoistedStruct struct {
+	typ *types.Struct
+	fieldContext
+}
+
+// pendingDiscriminator is a discriminator whose mapping is built from the
+// oneOf schemas of schema once every component is named. The schema shares
+// its oneOf schemas and extensions with the component it is copied into.
+type pendingDiscriminator struct {
+	decl          *typeDecl
+	discriminator *openapi3.Discriminator
+	schema        *openapi3.Schema
+	parsed        string
+	parser        string
+}
+
+// schemaResolver turns type checked declarations into openapi3 schemas.
+// Declarations are keyed by their *types.TypeName so that types with the same
+// name in different packages never collide.
+type schemaResolver struct {
+	opts      schemaOptions
+	schemas   openapi3.Schemas
+	decls     map[*types.TypeName]*typeDecl
+	structs   map[*types.Struct]structSyntax
+	enums     map[*types.TypeName][]enumConst
+	annotated []*typeDecl
+	instances []*types.Named
+	seen      map[string]bool
+	// components holds the type of each generated component by name
+	components map[string]*types.Named
+	// hoisted holds the anonymous structs generated as components by name
+	hoisted map[string]hoistedStruct
+	// field is the struct field whose type is being resolved
+	field fieldContext
+	// schemaMethods are the components whose schema is returned by the
+	// OpenAPISchema method of their type
+	schemaMethods []schemaMethodCall
+	// moduleDir is the directory of the module of the parsed packages, where
+	// OpenAPISchema methods are called from
+	moduleDir string
+	// discriminators are the discriminators to build the mapping of
+	discriminators []pendingDiscriminator
+	// diagnostics collects the problems found while resolving schemas
+	diagnostics Diagnostics
+}
+
+// newSchemaResolver creates a resolver for the annotated types of pkgs.
+// Types declared in the packages they import are resolved as well, as long
+// as those are loaded with syntax and belong to a module. Standard library
+// types are not API models and are left out.
+func newSchemaResolver(pkgs []*packages.Package, opts schemaOptions) *schemaResolver {
+	r := &schemaResolver{
+		opts:       opts,
+		schemas:    openapi3.Schemas{},
+		decls:      map[*types.TypeName]*typeDecl{},
+		structs:    map[*types.Struct]structSyntax{},
+		enums:      map[*types.TypeName][]enumConst{},
+		seen:       map[string]bool{},
+		components: map[string]*types.Named{},
+		hoisted:    map[string]hoistedStruct{},
+	}
+	roots := map[*packages.Package]bool{}
+	for _, pkg := range pkgs {
+		roots[pkg] = true
+		r.addPackage(pkg, true)
+		if r.moduleDir == "" && pkg.Module != nil {
+			r.moduleDir = pkg.Module.Dir
+		}
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if !roots[pkg] && pkg.Module != nil {
+			r.addPackage(pkg, false)
+		}
+	})
+	for _, pkg := range pkgs {
+		r.addInstances(pkg)
+	}
+
+	return r
+}
+
+func isAnnotated(doc string) bool {
+	return strings.Contains(doc, openapiSchemaDecoration) || strings.Contains(doc, swaggerSchemaDecoration)
+}
+
+// typeAnnotationKeys are the annotations supported in the doc comment of a
+// type declaration, oapi and swagger being the keys of oapi:schema and
+// swagger:model.
+var typeAnnotationKeys = map[string]bool{
+	"oapi":                             true,
+	"swagger":                          true,
+	"oapi_name":                        true,
+	"oapi_schema_json":                 true,
+	"oapi_discriminator":               true,
+	"oapi_discriminator_mapped_parser": true,
+	"oapi_discriminator_mapped_parsed": true,
+}
+
+// typeAnnotations returns the annotations in the doc comment of a type
+// declaration by key. When report is set, malformed annotations and unknown
+// keys are reported in pkg.
+func (r *schemaResolver) typeAnnotations(pkg *packages.Package, doc *ast.CommentGroup, report bool) map[string]string {
+	values := map[string]string{}
+	for _, line := range commentLines(doc) {
+		if !isAnnotationLine(line.text) {
+			continue
+		}
+		annotations, err := parseAnnotations(line.text)
+		if err != nil && report {
+			r.report(pkg, line.pos, SeverityError, "malformed annotation: %v", err)
+		}
+		for _, ann := range annotations {
+			if !typeAnnotationKeys[ann.key] {
+				if report {
+					r.report(pkg, line.pos, SeverityError, "unknown type annotation %s", ann.key)
+				}
+				continue
+			}
+			values[ann.key] = ann.value
+		}
+	}
+	return values
+}
+
+// addPackage collects every top level type declaration of pkg, together with
+// the syntax of all struct types so field docs and comments can be looked up
+// from the type checker's view of a struct. Only annotated declarations of
+// root packages are generated unconditionally.
+func (r *schemaResolver) addPackage(pkg *packages.Package, root bool) {
+	if pkg.TypesInfo == nil {
+		return
+	}
+	for _, f := range pkg.Syntax {
+		for _, d := range f.Decls {
+			decl, ok := d.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			r.addEnumConsts(pkg, decl)
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			doc := decl.Doc.Text()
+			annotated := isAnnotated(doc)
+			// annotations are checked once per declaration, whatever the
+			// number of types it declares
+			report := annotated || (root && strings.Contains(doc, "oapi_"))
+			annotations := r.typeAnnotations(pkg, decl.Doc, report)
+			for _, s := range decl.Specs {
+				spec, ok := s.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				obj, ok := pkg.TypesInfo.Defs[spec.Name].(*types.TypeName)
+				if !ok {
+					continue
+				}
+				description := docDescription(spec.Doc.Text())
+				if description == "" && !decl.Lparen.IsValid() {
+					description = docDescription(doc)
+				}
+				td := &typeDecl{
+					obj:         obj,
+					spec:        spec,
+					description: description,
+					annotations: annotations,
+					pkg:         pkg,
+					annotated:   annotated,
+				}
+				r.decls[obj] = td
+				if annotated && root {
+					r.annotated = append(r.annotated, td)
+				}
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				if typ, ok := pkg.TypesInfo.TypeOf(st).(*types.Struct); ok {
+					r.structs[typ] = structSyntax{node: st, pkg: pkg}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// addInstances collects the instantiations of annotated generic types used in
+// pkg, generic declarations only turn into components once instantiated.
+func (r *schemaResolver) addInstances(pkg *packages.Package) {
+	if pkg.TypesInfo == nil {
+		return
+	}
+	idents := []*ast.Ident{}
+	for ident, inst := range pkg.TypesInfo.Instances {
+		named, ok := inst.Type.(*types.Named)
+		if !ok || hasTypeParams(named) {
+			continue
+		}
+		if decl, ok := r.decls[named.Obj()]; ok && decl.annotated {
+			idents = append(idents, ident)
+		}
+	}
+	sort.Slice(idents, func(i, j int) bool {
+		return idents[i].Pos() < idents[j].Pos()
+	})
+	for _, ident := range idents {
+		r.instances = append(r.instances, pkg.TypesInfo.Instances[ident].Type.(*types.Named))
+	}
+}
+
+// hasTypeParams reports whether typ still depends on type parameters, like
+// Page[T] used inside the declaration of a generic type.
+func hasTypeParams(typ types.Type) bool {
+	switch t := types.Unalias(typ).(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return hasTypeParams(t.Elem())
+	case *types.Slice:
+		return hasTypeParams(t.Elem())
+	case *types.Array:
+		return hasTypeParams(t.Elem())
+	case *types.Map:
+		return hasTypeParams(t.Key()) || hasTypeParams(t.Elem())
+	case *types.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if hasTypeParams(t.TypeArgs().At(i)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// syntaxStruct returns the struct whose syntax declares the fields of st, the
+// struct underlying named. For instantiated generic types and types defined
+// from them this is the struct of the generic declaration.
+func (r *schemaResolver) syntaxStruct(named *types.Named, st *types.Struct) *types.Struct {
+	if _, ok := r.structs[st]; ok {
+		return st
+	}
+	named = named.Origin()
+	decl, ok := r.decls[named.Obj()]
+	if !ok {
+		return st
+	}
+	if origin, ok := named.Underlying().(*types.Struct); ok {
+		if _, ok := r.structs[origin]; ok {
+			return origin
+		}
+	}
+	if rhs, ok := types.Unalias(decl.pkg.TypesInfo.TypeOf(decl.spec.Type)).(*types.Named); ok {
+		return r.syntaxStruct(rhs, st)
+	}
+
+	return st
+}
+
+// structFields returns the syntax of each field of st, indexed like st.Field.
+// Entries are nil when the struct was not declared in a loaded package.
+func (r *schemaResolver) structFields(st *types.Struct) []*ast.Field {
+	fields := make([]*ast.Field, st.NumFields())
+	syntax, ok := r.structs[st]
+	if !ok {
+		return fields
+	}
+	i := 0
+	for _, f := range syntax.node.Fields.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for j := 0; j < n && i < len(fields); j++ {
+			fields[i] = f
+			i++
+		}
+	}
+
+	return fields
+}
+
+// component registers the component schema for typ, declared by decl, and
+// returns a $ref to it. Declarations which do not produce a named schema, like
+// aliases and named pointer types, are inlined.
+//
+// A component is marked as seen before its schema is resolved, so types which
+// refer to themselves, directly or through other types, get a $ref to the
+// component being resolved instead of recursing. Every cycle between types is
+// broken by a $ref this way, whatever the order the types are reached in.
+func (r *schemaResolver) component(decl *typeDecl, typ types.Type) *openapi3.SchemaRef {
+	named, ok := typ.(*types.Named)
+	if !ok || decl.obj.IsAlias() {
+		return r.resolveType(decl.obj.Type())
+	}
+	if ptr, ok := named.Underlying().(*types.Pointer); ok {
+		return r.resolveType(ptr.Elem())
+	}
+	name := r.componentName(named)
+	if r.seen[name] {
+		if other := r.conflictingType(name, named); other != nil {
+			r.report(decl.pkg, decl.obj.Pos(), SeverityError, "component name %s of %s is already used by %s, rename it with oapi_name or use another naming strategy", name, named, other)
+		}
+		return openapi3.NewSchemaRef(createRef(name), nil)
+	}
+	r.seen[name] = true
+	r.components[name] = named
+	if schema, ok := r.overriddenSchema(decl, named, name); ok {
+		r.schemas[name] = openapi3.NewSchemaRef("", schema)
+		return openapi3.NewSchemaRef(createRef(name), nil)
+	}
+	// anonymous structs of the declaration are named after its own fields
+	field := r.field
+	r.field = fieldContext{}
+	n, schema := r.resolveNamedSchema(decl, named)
+	r.field = field
+	if n == nil {
+		delete(r.seen, name)
+		delete(r.components, name)
+		return openapi3.NewSchemaRef("", &schema)
+	}
+	r.schemas[*n] = openapi3.NewSchemaRef("", &schema)
+
+	return openapi3.NewSchemaRef(createRef(*n), nil)
+}
+
+// conflictingType returns the type already generated as the component name
+// when it is not typ.
+func (r *schemaResolver) conflictingType(name string, typ types.Type) types.Type {
+	if other, ok := r.components[name]; ok && !types.Identical(other, typ) {
+		return other
+	}
+	if other, ok := r.hoisted[name]; ok && !types.Identical(other.typ, typ) {
+		return other.typ
+	}
+	return nil
+}
+
+// anonymousStruct resolves a struct type without a name. It is inlined unless
+// hoisting is enabled, then it becomes a component named after the field
+// declaring it, like OrderMeta for the Meta field of Order.
+func (r *schemaResolver) anonymousStruct(st *types.Struct) *openapi3.SchemaRef {
+	field := r.field
+	name := field.parent + field.field
+	schema := &openapi3.Schema{
+		Required: []string{},
+	}
+	if !r.opts.hoistStructs || field.parent == "" || field.field == "" {
+		r.resolveStruct(schema, nil, name, st, st)
+		return openapi3.NewSchemaRef("", schema)
+	}
+	if r.seen[name] {
+		if other := r.conflictingType(name, st); other != nil {
+			syntax := r.structs[st]
+			var pos token.Pos
+			if syntax.node != nil {
+				pos = syntax.node.Pos()
+			}
+			r.report(syntax.pkg, pos, SeverityError, "component name %s of the anonymous struct of field %s is already used by %s, rename the field or turn the struct into a named type", name, field.field, other)
+		}
+		return openapi3.NewSchemaRef(createRef(name), nil)
+	}
+	r.seen[name] = true
+	r.hoisted[name] = hoistedStruct{typ: st, fieldContext: field}
+	r.resolveStruct(schema, nil, name, st, st)
+	r.schemas[name] = openapi3.NewSchemaRef("", schema)
+
+	return openapi3.NewSchemaRef(createRef(name), nil)
+}
+
+// implementations returns the annotated struct types of the loaded packages
+// which, or pointers to which, implement iface, ordered by package and
+// declaration.
+func (r *schemaResolver) implementations(iface *types.Interface) []*types.Named {
+	variants := []*types.Named{}
+	for obj, decl := range r.decls {
+		named, ok := obj.Type().(*types.Named)
+		if !ok || !decl.annotated || obj.IsAlias() || named.TypeParams().Len() > 0 {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		if types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface) {
+			variants = append(variants, named)
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		a, b := variants[i].Obj(), variants[j].Obj()
+		if a.Pkg().Path() != b.Pkg().Path() {
+			return a.Pkg().Path() < b.Pkg().Path()
+		}
+		return a.Pos() < b.Pos()
+	})
+
+	return variants
+}
+
+// resolveField resolves the schema of a struct field and whether its type
+// alone makes it required: pointers, slices, arrays and maps may be left out.
+// This is the byType input of fieldRequirement, which decides according to
+// the RequiredStrategy.
+func (r *schemaResolver) resolveField(typ types.Type) (*openapi3.SchemaRef, bool) {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Pointer:
+		fieldSchema, _ := r.resolveField(t.Elem())
+		return fieldSchema, false
+	case *types.Slice, *types.Array, *types.Map:
+		return r.resolveType(typ), false
+	}
+
+	return r.resolveType(typ), true
+}
+
+func (r *schemaResolver) resolveType(typ types.Type) *openapi3.SchemaRef {
+	if alias, ok := typ.(*types.Alias); ok {
+		if schema, ok := r.mappedSchema(alias.Obj()); ok {
+			return openapi3.NewSchemaRef("", schema)
+		}
+	}
+	switch t := types.Unalias(typ).(type) {
+	case *types.Basic:
+		return openapi3.NewSchemaRef("", basicSchema(t))
+	case *types.Pointer:
+		return r.resolveType(t.Elem())
+	case *types.Slice:
+		if isByteSlice(t) {
+			return openapi3.NewSchemaRef("", openapi3.NewBytesSchema())
+		}
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:  &openapi3.Types{"array"},
+			Items: r.resolveType(t.Elem()),
+		})
+	case *types.Array:
+		length := uint64(t.Len())
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:     &openapi3.Types{"array"},
+			Items:    r.resolveType(t.Elem()),
+			MinItems: length,
+			MaxItems: &length,
+		})
+	case *types.Map:
+		return openapi3.NewSchemaRef("", &openapi3.Schema{
+			Type:                 &openapi3.Types{"object"},
+			AdditionalProperties: r.mapValues(t),
+		})
+	case *types.Struct:
+		return r.anonymousStruct(t)
+	case *types.Named:
+		if schema, ok := r.mappedSchema(t.Obj()); ok {
+			return openapi3.NewSchemaRef("", schema)
+		}
+		if decl, ok := r.decls[t.Obj()]; ok && !hasTypeParams(t) {
+			return r.component(decl, t)
+		}
+		// Types declared outside the loaded packages
+		if b, ok := t.Underlying().(*types.Basic); ok {
+			return r.resolveType(b)
+		}
+	}
+
+	return openapi3.NewSchemaRef("", &openapi3.Schema{
+		Type: &openapi3.Types{"object"},
+	})
+}
+
+// mapValues returns the schema of the values of a map, maps of empty
+// interfaces allow any value.
+func (r *schemaResolver) mapValues(t *types.Map) openapi3.AdditionalProperties {
+	if iface, ok := types.Unalias(t.Elem()).Underlying().(*types.Interface); ok && iface.Empty() {
+		allowed := true
+		return openapi3.AdditionalProperties{Has: &allowed}
+	}
+
+	return openapi3.AdditionalProperties{Schema: r.resolveType(t.Elem())}
+}
@@ -3,492 +3,598 @@ package openapi3Struct
 import (
 	"fmt"
 	"go/ast"
+	"go/types"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
 )
 
-// See https://regex101.com/r/8SGj7m/1
-var tagReqexp = regexp.MustCompile(`([^  \x60\n][a-zA-z0-9_-]+):"? ?([ a-zA-z0-9{},_-]+)"? ?`)
+func (r *schemaResolver) resolveSchema(decl *typeDecl) (*string, openapi3.Schema) {
+	return r.resolveNamedSchema(decl, decl.obj.Type())
+}
+
+// resolveNamedSchema resolves the schema of typ, which is the type declared by
+// decl or an instantiation of it when decl is a generic type.
+func (r *schemaResolver) resolveNamedSchema(decl *typeDecl, typ types.Type) (*string, openapi3.Schema) {
+	name, schema := r.resolveDeclaredSchema(decl, typ)
+	if name != nil && decl.description != "" {
+		schema.Description = decl.description
+		if r.opts.docTitles {
+			schema.Title = firstSentence(decl.description)
+		}
+	}
+
+	return name, schema
+}
 
-func resolveSchema(schemas openapi3.Schemas, s ast.Spec, doc string, declarationMap map[string]*ast.TypeSpec) (*string, openapi3.Schema) {
+func (r *schemaResolver) resolveDeclaredSchema(decl *typeDecl, typ types.Type) (*string, openapi3.Schema) {
 	schema := openapi3.Schema{
 		Required: []string{},
 	}
-	discriminatorPropertyName := ""
-	discriminatorParsed := ""
-	discriminatorParser := ""
+	discriminatorPropertyName := decl.annotations["oapi_discriminator"]
+	discriminatorParsed := decl.annotations["oapi_discriminator_mapped_parsed"]
+	discriminatorParser := decl.annotations["oapi_discriminator_mapped_parser"]
+
+	if decl.obj.IsAlias() {
+		// Type alias (e.g. type X = string): return without a name
+		// so it inlines the aliased type rather than creating a named schema.
+		ref := r.resolveType(decl.obj.Type())
+		if ref.Value == nil {
+			return nil, openapi3.Schema{Type: &openapi3.Types{"object"}}
+		}
+		return nil, *ref.Value
+	}
 
-	if strings.Contains(doc, "oapi_discriminator") {
-		for _, line := range strings.Split(doc, "\n") {
-			matches := tagReqexp.FindAllStringSubmatch(line, -1)
-			for _, match := range matches {
-				if match[1] == "oapi_discriminator" {
-					discriminatorPropertyName = match[2]
-				}
-				if match[1] == "oapi_discriminator_mapped_parser" {
-					discriminatorParser = match[2]
-				}
-				if match[1] == "oapi_discriminator_mapped_parsed" {
-					discriminatorParsed = match[2]
-				}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil, schema
+	}
+	name := r.componentName(named)
+	if mapped, ok := r.mappedSchema(decl.obj); ok {
+		return &name, *mapped
+	}
+
+	switch st := named.Underlying().(type) {
+	case *types.Signature, *types.Chan:
+		r.report(decl.pkg, decl.obj.Pos(), SeverityWarning, "type %s can not be encoded as JSON", decl.obj.Name())
+	case *types.Struct:
+		r.resolveStruct(&schema, decl.pkg, name, st, r.syntaxStruct(named, st))
+		if discriminatorPropertyName != "" {
+			schema.Discriminator = r.discriminatorOf(decl, &schema, discriminatorPropertyName, discriminatorParsed, discriminatorParser)
+		}
+		return &name, schema
+	case *types.Slice:
+		if isByteSlice(st) {
+			schema.Type = &openapi3.Types{"string"}
+			schema.Format = "byte"
+			return &name, schema
+		}
+		schema.Type = &openapi3.Types{"array"}
+		schema.Items = r.resolveType(st.Elem())
+		return &name, schema
+	case *types.Array:
+		length := uint64(st.Len())
+		schema.Type = &openapi3.Types{"array"}
+		schema.Items = r.resolveType(st.Elem())
+		schema.MinItems = length
+		schema.MaxItems = &length
+		return &name, schema
+	case *types.Map:
+		schema.Type = &openapi3.Types{"object"}
+		schema.AdditionalProperties = r.mapValues(st)
+		return &name, schema
+	case *types.Interface:
+		if !decl.annotated || st.Empty() {
+			schema.Type = &openapi3.Types{"object"}
+			return &name, schema
+		}
+		// annotated interfaces are one of the annotated structs implementing them
+		for _, variant := range r.implementations(st) {
+			schema.OneOf = append(schema.OneOf, r.resolveType(variant))
+		}
+		if len(schema.OneOf) == 0 {
+			r.report(decl.pkg, decl.obj.Pos(), SeverityWarning, "interface %s has no annotated implementations", decl.obj.Name())
+			schema.Type = &openapi3.Types{"object"}
+			return &name, schema
+		}
+		if discriminatorPropertyName != "" {
+			if discriminatorParsed == "" {
+				discriminatorParsed = "oneOf"
 			}
+			schema.Discriminator = r.discriminatorOf(decl, &schema, discriminatorPropertyName, discriminatorParsed, discriminatorParser)
 		}
+		return &name, schema
+	case *types.Basic:
+		schema := *basicSchema(st)
+		r.applyEnum(&schema, decl.obj)
+		return &name, schema
 	}
-	switch s := s.(type) {
-	case *ast.TypeSpec:
-		switch st := s.Type.(type) {
-		case *ast.FuncType:
-		case *ast.StructType:
-			schema.Type = &openapi3.Types{"object"}
+	return nil, schema
+}
 
-			containsOneOf := false
-			containsAllOf := false
-			fields := openapi3.Schemas{}
-			for _, f := range st.Fields.List {
-				oneOf := false
-				oneOfMapping := ""
-				allOf := false
-
-				name := ""
-				if len(f.Names) != 0 {
-					name = f.Names[0].Name
-				}
-				fieldSchema, required := resolveField(schemas, f, f.Type, declarationMap)
+// discriminatorOf returns the discriminator on propertyName of schema,
+// declared by decl. When parsed is oneOf, its mapping is built by
+// mapDiscriminators once the components have their final names.
+func (r *schemaResolver) discriminatorOf(decl *typeDecl, schema *openapi3.Schema, propertyName, parsed, parser string) *openapi3.Discriminator {
+	discriminator := &openapi3.Discriminator{
+		PropertyName: propertyName,
+	}
+	if parsed != "" {
+		r.discriminators = append(r.discriminators, pendingDiscriminator{
+			decl:          decl,
+			discriminator: discriminator,
+			schema:        schema,
+			parsed:        parsed,
+			parser:        parser,
+		})
+	}
 
-				if f.Tag != nil {
-					matches := tagReqexp.FindAllStringSubmatch(f.Tag.Value, -1)
-					for _, match := range matches {
-						if len(match) != 3 {
-							continue
-						}
-						if match[1] == "json" {
-							// we only want the first part, it can contain things like "omitempty" and we want to ignore these
-							// TODO we should parse "omitempty" as optional
-							split := strings.Split(match[2], ",")
-							name = split[0]
-						}
+	return discriminator
+}
 
-						// Handle oapi tag
-						if strings.HasPrefix(match[1], "oapi") {
-							requiredAttr := updateSchemaAttribute(fieldSchema, match[0])
-							if requiredAttr {
-								required = true
-							}
-						}
-					}
+// mapDiscriminators builds the mappings of the discriminators returned by
+// discriminatorOf. The oneOf schemas are keyed by their x-oneOf-mappings
+// entry, or by the name of their type without qualification, its oapi_name
+// if any, which the transformers of parser apply to. The keys are the same
+// with every naming strategy, only the $refs follow the final component names.
+func (r *schemaResolver) mapDiscriminators() {
+	for _, pending := range r.discriminators {
+		mapping := openapi3.StringMap{}
+		if pending.parsed != "oneOf" {
+			pending.discriminator.Mapping = mapping
+			continue
+		}
+		transform := func(key string) string { return key }
+		if pending.parser != "" {
+			parserTransform, err := r.keyTransformer(pending.parser)
+			if err != nil {
+				r.report(pending.decl.pkg, pending.decl.obj.Pos(), SeverityError, "%v", err)
+			} else {
+				transform = parserTransform
+			}
+		}
+		mappings, _ := pending.schema.Extensions["x-oneOf-mappings"].(map[string]string)
+		for _, s := range pending.schema.OneOf {
+			if s.Ref == "" {
+				continue
+			}
+			key, ok := mappings[s.Ref]
+			if !ok {
+				// discriminator values are part of the payload, they do
+				// not depend on the naming strategy
+				name := strings.TrimPrefix(s.Ref, createRef(""))
+				if named, ok := r.components[name]; ok {
+					name = r.bareName(named)
 				}
+				key = transform(name)
+			}
+			if other, ok := mapping[key]; ok && other != s.Ref {
+				r.report(pending.decl.pkg, pending.decl.obj.Pos(), SeverityError, "discriminator value %s of %s maps to both %s and %s, rename one of them with oapi_name", key, pending.decl.obj.Name(), other, s.Ref)
+				continue
+			}
+			mapping[key] = s.Ref
+		}
+		pending.discriminator.Mapping = mapping
+	}
+}
 
-				if f.Comment != nil {
+// resolveStruct fills schema with the fields of st, whose syntax is the one
+// of syntax. Diagnostics are reported in pkg unless the package declaring the
+// syntax is known. Anonymous structs of its fields are named after parent
+// when hoisted.
+func (r *schemaResolver) resolveStruct(schema *openapi3.Schema, pkg *packages.Package, parent string, st *types.Struct, syntax *types.Struct) {
+	schema.Type = &openapi3.Types{"object"}
+
+	containsOneOf := false
+	containsAllOf := false
+	fields := openapi3.Schemas{}
+	if declared, ok := r.structs[syntax]; ok {
+		pkg = declared.pkg
+	}
+	for _, jf := range r.jsonFields(pkg, st, syntax) {
+		field := jf.field
+		f := jf.syntax
+		tag := jf.tag
+		pkg := jf.pkg
+		oneOf := false
+		oneOfMapping := ""
+		allOf := false
+
+		jsonTag := parseJSONTag(tag)
+		if jsonTag.skip {
+			continue
+		}
+		name := jf.name
+		r.field = fieldContext{parent: parent, field: field.Name()}
+		fieldSchema, required := r.resolveField(field.Type())
+		if unsupported := unsupportedType(field.Type()); unsupported != nil {
+			r.report(pkg, field.Pos(), SeverityWarning, "field %s of type %s can not be encoded as JSON", field.Name(), unsupported)
+		}
+		if jsonTag.asString && isStringEncodable(field.Type()) {
+			fieldSchema = openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+		}
+		fieldSchema = r.applyValidateTag(fieldSchema, field.Type(), tag)
+		explicitlyRequired := false
+		// an oapi_description annotation wins over the doc of the field
+		explicitDescription := false
+
+		tagPos := field.Pos()
+		if f != nil && f.Tag != nil {
+			tagPos = f.Tag.Pos()
+		}
+		annotations, err := parseStructTag(tag)
+		if err != nil {
+			r.report(pkg, tagPos, SeverityError, "malformed struct tag of field %s: %v", field.Name(), err)
+		}
+		for _, ann := range annotations {
+			// Handle oapi tag
+			if strings.HasPrefix(ann.key, "oapi_") {
+				if ann.key == "oapi_description" {
+					explicitDescription = true
 				}
-
-				if f.Doc != nil {
-					for _, line := range strings.Split(f.Doc.Text(), "\n") {
-						if strings.HasPrefix(line, "oapi") {
-							if strings.Contains(line, "oapi_oneOf") {
-								oneOf = true
-								if strings.Contains(line, ":") {
-									split := strings.Split(line, ":")
-									oneOfMapping = strings.Trim(split[1], " ")
-								}
-								continue
-							}
-							if line == "oapi_allOf" {
-								allOf = true
-								continue
-							}
-							requiredAttr := updateSchemaAttribute(fieldSchema, line)
-							if requiredAttr {
-								required = true
-							}
-						}
-					}
+				requiredAttr, err := updateSchemaAttribute(fieldSchema, ann)
+				if err != nil {
+					r.report(pkg, tagPos, SeverityError, "%v", err)
 				}
-				if name == "" && !oneOf {
-					allOf = true
+				if requiredAttr {
+					explicitlyRequired = true
 				}
+			}
+		}
 
-				if name != "" {
-					fields[name] = fieldSchema
-					if required {
-						schema.Required = append(schema.Required, name)
-					}
+		if f != nil {
+			for _, line := range commentLines(f.Doc) {
+				if !strings.HasPrefix(line.text, "oapi") {
 					continue
 				}
-				if oneOf {
-					schema.OneOf = append(schema.OneOf, fieldSchema)
-					containsOneOf = true
-					if oneOfMapping != "" {
-						// TODO refactor this, it's ugly
-						if schema.Extensions == nil {
-							schema.Extensions = map[string]any{}
+				annotations, err := parseAnnotations(line.text)
+				if err != nil {
+					r.report(pkg, line.pos, SeverityError, "malformed annotation: %v", err)
+				}
+				for _, ann := range annotations {
+					switch {
+					case ann.key == "oapi_oneOf":
+						oneOf = true
+						oneOfMapping = ann.value
+					case ann.key == "oapi_allOf":
+						allOf = true
+					case strings.HasPrefix(ann.key, "oapi_"):
+						if ann.key == "oapi_description" {
+							explicitDescription = true
+						}
+						requiredAttr, err := updateSchemaAttribute(fieldSchema, ann)
+						if err != nil {
+							r.report(pkg, line.pos, SeverityError, "%v", err)
 						}
-						if _, ok := schema.Extensions["x-oneOf-mappings"]; !ok {
-							schema.Extensions["x-oneOf-mappings"] = map[string]string{}
+						if requiredAttr {
+							explicitlyRequired = true
 						}
-						schema.Extensions["x-oneOf-mappings"].(map[string]string)[fieldSchema.Ref] = oneOfMapping
 					}
 				}
-				if allOf {
-					schema.AllOf = append(schema.AllOf, fieldSchema)
-					containsAllOf = true
-				}
 			}
+		}
+		if description := fieldDescription(f); description != "" && name != "" && !explicitDescription {
+			fieldSchema = r.describedSchema(fieldSchema, description)
+		}
+		required, nullable := r.fieldRequirement(field.Type(), tag, jsonTag, required, explicitlyRequired)
+		if jf.optional && !explicitlyRequired {
+			required = false
+		}
+		if nullable {
+			fieldSchema = r.nullableSchema(fieldSchema)
+		}
+		if name == "" && !oneOf {
+			allOf = true
+		}
 
-			if containsOneOf {
-				if len(fields) != 0 {
-					schema.OneOf = append(schema.OneOf, openapi3.NewSchemaRef("", &openapi3.Schema{
-						Type:       &openapi3.Types{"object"},
-						Properties: fields,
-					}))
-				}
-			} else if containsAllOf {
-				if len(fields) != 0 {
-					schema.AllOf = append(schema.AllOf, openapi3.NewSchemaRef("", &openapi3.Schema{
-						Type:       &openapi3.Types{"object"},
-						Properties: fields,
-					}))
-				}
-			} else {
-				schema.Properties = fields
+		if name != "" {
+			fields[name] = fieldSchema
+			if required {
+				schema.Required = append(schema.Required, name)
 			}
-			if discriminatorPropertyName != "" {
-				discriminator := openapi3.Discriminator{
-					PropertyName: discriminatorPropertyName,
-				}
-				if discriminatorParsed != "" {
-					mapping := map[string]string{}
-					if discriminatorParsed == "oneOf" {
-						for _, s := range schema.OneOf {
-							if mappings, ok := schema.Extensions["x-oneOf-mappings"]; ok {
-								// TODO maybe add check
-								mappings := mappings.(map[string]string)
-								if oneOfMapping, ok := mappings[s.Ref]; ok {
-									if discriminatorParser != "" {
-										mapping["nomap:"+oneOfMapping] = s.Ref
-									} else {
-										mapping[oneOfMapping] = s.Ref
-									}
-
-									continue
-								}
-							}
-
-							if s.Ref != "" {
-								parts := strings.Split(s.Ref, "/")
-								key := parts[len(parts)-1]
-								mapping[key] = s.Ref
-							}
-						}
-					}
-					discriminator.Mapping = mapping
-				}
-				if discriminatorParser == "upperSnake" {
-					if discriminator.Mapping != nil {
-						parsedMapping := map[string]string{}
-						for key, value := range discriminator.Mapping {
-							if strings.HasPrefix(key, "nomap:") {
-								parsedMapping[strings.TrimPrefix(key, "nomap:")] = value
-								continue
-							}
-							parsedMapping[toSnakeUpperCase(key)] = value
-						}
-
-						discriminator.Mapping = parsedMapping
-					}
+			continue
+		}
+		if oneOf {
+			schema.OneOf = append(schema.OneOf, fieldSchema)
+			containsOneOf = true
+			if oneOfMapping != "" {
+				// TODO refactor this, it's ugly
+				if schema.Extensions == nil {
+					schema.Extensions = map[string]any{}
 				}
-				schema.Discriminator = &discriminator
-			}
-			return &s.Name.Name, schema
-		case *ast.ArrayType:
-			el := st.Elt
-			if star, ok := el.(*ast.StarExpr); ok {
-				el = star.X
-			}
-			var itemsRef *openapi3.SchemaRef
-			switch elt := el.(type) {
-			case *ast.Ident:
-				if _, ok := declarationMap[elt.Name]; ok {
-					itemsRef = openapi3.NewSchemaRef(createRef(elt.Name), nil)
-				} else {
-					itemsRef = openapi3.NewSchemaRef("", &openapi3.Schema{
-						Type: &openapi3.Types{resolvePrimitiveType(elt.Name)},
-					})
+				if _, ok := schema.Extensions["x-oneOf-mappings"]; !ok {
+					schema.Extensions["x-oneOf-mappings"] = map[string]string{}
 				}
-			case *ast.SelectorExpr, *ast.MapType:
-				// External types (e.g. time.Time) and map elements: treat as object.
-				itemsRef = openapi3.NewSchemaRef("", &openapi3.Schema{
-					Type: &openapi3.Types{"object"},
-				})
-			default:
-				itemsRef = openapi3.NewSchemaRef("", &openapi3.Schema{
-					Type: &openapi3.Types{"object"},
-				})
-			}
-			schema.Type = &openapi3.Types{"array"}
-			schema.Items = itemsRef
-			return &s.Name.Name, schema
-		case *ast.MapType:
-			schema.Type = &openapi3.Types{"object"}
-			return &s.Name.Name, schema
-		case *ast.InterfaceType:
-			schema.Type = &openapi3.Types{"object"}
-			return &s.Name.Name, schema
-		case *ast.SelectorExpr:
-		default:
-			ident, ok := s.Type.(*ast.Ident)
-			if !ok {
-				break
-			}
-			resolved := resolvePrimitiveType(ident.Name)
-			schema := openapi3.Schema{
-				Type: &openapi3.Types{resolved},
-			}
-			if s.Assign.IsValid() {
-				// Type alias (e.g. type X = string): return without a name
-				// so it inlines the primitive type rather than creating a named schema.
-				return nil, schema
+				schema.Extensions["x-oneOf-mappings"].(map[string]string)[fieldSchema.Ref] = oneOfMapping
 			}
-			return &s.Name.Name, schema
+		}
+		if allOf {
+			schema.AllOf = append(schema.AllOf, fieldSchema)
+			containsAllOf = true
 		}
 	}
-	return nil, schema
+
+	if containsOneOf {
+		if len(fields) != 0 {
+			schema.OneOf = append(schema.OneOf, openapi3.NewSchemaRef("", &openapi3.Schema{
+				Type:       &openapi3.Types{"object"},
+				Properties: fields,
+			}))
+		}
+	} else if containsAllOf {
+		if len(fields) != 0 {
+			schema.AllOf = append(schema.AllOf, openapi3.NewSchemaRef("", &openapi3.Schema{
+				Type:       &openapi3.Types{"object"},
+				Properties: fields,
+			}))
+		}
+	} else {
+		schema.Properties = fields
+	}
 }
 
-func updateSchemaAttribute(fieldSchema *openapi3.SchemaRef, keyValue string) bool {
-	if fieldSchema.Value == nil {
-		return false
+// isAnnotationLine reports whether a doc comment line is an annotation, like
+// oapi:schema or oapi_required:"true", rather than documentation.
+func isAnnotationLine(line string) bool {
+	return strings.HasPrefix(line, "oapi") || strings.HasPrefix(line, "swagger:")
+}
+
+// docDescription returns the documentation of a doc comment without its
+// annotation lines.
+func docDescription(doc string) string {
+	lines := []string{}
+	for _, line := range strings.Split(doc, "\n") {
+		if isAnnotationLine(strings.TrimSpace(line)) {
+			continue
+		}
+		lines = append(lines, line)
 	}
-	fullMatch := tagReqexp.FindAllStringSubmatch(keyValue, -1)
-	if len(fullMatch) != 1 {
-		// TODO handle error
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// fieldDescription returns the description of a struct field from its doc
+// comment, or from its line comment when it has no doc comment.
+func fieldDescription(f *ast.Field) string {
+	if f == nil {
+		return ""
+	}
+	if description := docDescription(f.Doc.Text()); description != "" {
+		return description
+	}
+	return docDescription(f.Comment.Text())
+}
+
+// firstSentence returns the first sentence of a description, which ends with
+// a period followed by a space or at the end of the first paragraph.
+func firstSentence(description string) string {
+	paragraph, _, _ := strings.Cut(description, "\n\n")
+	paragraph = strings.Join(strings.Fields(paragraph), " ")
+	if i := strings.Index(paragraph, ". "); i >= 0 {
+		return paragraph[:i+1]
+	}
+	return paragraph
+}
+
+// describedSchema returns fieldSchema with the given description, and the
+// first sentence of it as title when enabled.
+func (r *schemaResolver) describedSchema(fieldSchema *openapi3.SchemaRef, description string) *openapi3.SchemaRef {
+	schema := withSiblings(fieldSchema)
+	schema.Description = description
+	if r.opts.docTitles {
+		schema.Title = firstSentence(description)
+	}
+
+	return openapi3.NewSchemaRef("", schema)
+}
+
+// withSiblings returns a copy of the schema of fieldSchema which keywords can
+// be added to, references are wrapped in allOf since sibling keywords of $ref
+// are ignored.
+func withSiblings(fieldSchema *openapi3.SchemaRef) *openapi3.Schema {
+	if fieldSchema.Ref != "" || fieldSchema.Value == nil {
+		return &openapi3.Schema{
+			AllOf: openapi3.SchemaRefs{fieldSchema},
+		}
+	}
+	schema := *fieldSchema.Value
+	return &schema
+}
+
+// jsonTag holds the options of a json struct tag, following encoding/json
+type jsonTag struct {
+	name string
+	// skip is set for json:"-"
+	skip bool
+	// omitEmpty is set for omitempty and omitzero, the field can be left out
+	omitEmpty bool
+	// asString is set for the ",string" option
+	asString bool
+}
+
+func parseJSONTag(tag string) jsonTag {
+	value, ok := reflect.StructTag(tag).Lookup("json")
+	if !ok {
+		return jsonTag{}
 	}
-	match := fullMatch[0]
-	attrs := strings.Split(match[1], "_")
-	// TODO handle error
-	if len(attrs) != 2 {
+	if value == "-" {
+		return jsonTag{skip: true}
 	}
-	attrName := attrs[1]
-	attrName = strings.ToUpper(string(attrs[1][0])) + string(attrName[1:])
-	if attrName == "Required" {
-		if match[2] == "true" {
-			return true
+	options := strings.Split(value, ",")
+	parsed := jsonTag{name: options[0]}
+	for _, option := range options[1:] {
+		switch option {
+		case "omitempty", "omitzero":
+			parsed.omitEmpty = true
+		case "string":
+			parsed.asString = true
 		}
+	}
 
+	return parsed
+}
+
+// isEmbeddedStruct reports whether field is an embedded struct, encoding/json
+// promotes the exported fields of those even when the type is unexported.
+func isEmbeddedStruct(field *types.Var) bool {
+	if !field.Embedded() {
 		return false
 	}
+	_, ok := field.Type().Underlying().(*types.Struct)
+	return ok
+}
+
+// isStringEncodable reports whether the ",string" json option applies to typ,
+// which encoding/json only does for numbers and booleans, or pointers to them.
+func isStringEncodable(typ types.Type) bool {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsNumeric|types.IsBoolean) != 0
+}
 
-	rfValue := reflect.ValueOf(fieldSchema.Value).Elem()
-	fv := rfValue.FieldByName(attrName)
-	fvType := fv.Type().String()
-	pointer := false
-	if strings.HasPrefix(fvType, "*") {
-		pointer = true
-		fvType = string(fvType[1:])
+// updateSchemaAttribute sets the schema keyword named by an oapi_<attribute>
+// annotation on fieldSchema and reports whether the annotation marks the field
+// as required. Attributes are looked up by their OpenAPI keyword, like
+// oapi_maxLength, or by the name of the openapi3.Schema field, like oapi_min.
+func updateSchemaAttribute(fieldSchema *openapi3.SchemaRef, ann annotation) (bool, error) {
+	attrName, ok := strings.CutPrefix(ann.key, "oapi_")
+	if !ok || attrName == "" {
+		return false, fmt.Errorf("unknown schema attribute %q", ann.key)
 	}
-	switch fvType {
-	case "bool":
-		bool, err := strconv.ParseBool(match[2])
+	if attrName == "required" {
+		required, err := strconv.ParseBool(ann.value)
 		if err != nil {
-			// TODO handle error
+			return false, fmt.Errorf("invalid value %q of %s: expected a boolean", ann.value, ann.key)
 		}
-		if pointer {
-			fv.Set(reflect.ValueOf(&bool))
-		} else {
-			fv.Set(reflect.ValueOf(bool))
+		return required, nil
+	}
+
+	// fieldSchema is updated in place, so a $ref is wrapped as a copy
+	original := *fieldSchema
+	schema := withSiblings(&original)
+	fv, ok := schemaAttribute(reflect.ValueOf(schema).Elem(), attrName)
+	if !ok {
+		return false, fmt.Errorf("unknown schema attribute %q", ann.key)
+	}
+	fvType := fv.Type()
+	pointer := fvType.Kind() == reflect.Pointer
+	if pointer {
+		fvType = fvType.Elem()
+	}
+	var value reflect.Value
+	switch {
+	case fvType == reflect.TypeOf(openapi3.Types{}):
+		schemaTypes := openapi3.Types{}
+		for _, v := range strings.Split(ann.value, ",") {
+			schemaTypes = append(schemaTypes, strings.TrimSpace(v))
 		}
-	case "float", "float32", "float64":
-		float, err := strconv.ParseFloat(match[2], 64)
+		value = reflect.ValueOf(schemaTypes)
+	case fvType.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(ann.value)
 		if err != nil {
-			// TODO handle error
-		}
-		if pointer {
-			fv.Set(reflect.ValueOf(&float))
-		} else {
-			fv.Set(reflect.ValueOf(float))
+			return false, fmt.Errorf("invalid value %q of %s: expected a boolean", ann.value, ann.key)
 		}
-	case "uint64":
-		uint, err := strconv.ParseUint(match[2], 10, 64)
+		value = reflect.ValueOf(b)
+	case fvType.Kind() == reflect.Float64:
+		float, err := strconv.ParseFloat(ann.value, 64)
 		if err != nil {
-			// TODO handle error
+			return false, fmt.Errorf("invalid value %q of %s: expected a number", ann.value, ann.key)
 		}
-		if pointer {
-			fv.Set(reflect.ValueOf(&uint))
-		} else {
-			fv.Set(reflect.ValueOf(uint))
+		value = reflect.ValueOf(float)
+	case fvType.Kind() == reflect.Uint64:
+		uint, err := strconv.ParseUint(ann.value, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid value %q of %s: expected a non-negative integer", ann.value, ann.key)
 		}
-	case "[]interface {}":
+		value = reflect.ValueOf(uint)
+	case fvType == reflect.TypeOf([]any{}):
 		newValue := []any{}
 		currentValue, ok := fv.Interface().([]any)
 		if ok {
 			newValue = append(newValue, currentValue...)
 		}
-		values := strings.Split(match[2], ",")
-		for _, v := range values {
+		for _, v := range strings.Split(ann.value, ",") {
 			newValue = append(newValue, strings.TrimSpace(v))
 		}
-		fv.Set(reflect.ValueOf(newValue))
+		value = reflect.ValueOf(newValue)
+	case fvType.Kind() == reflect.String, fvType.Kind() == reflect.Interface:
+		value = reflect.ValueOf(ann.value)
 	default:
-		if pointer {
-			fv.Set(reflect.ValueOf(&match[2]))
-		} else {
-			fv.Set(reflect.ValueOf(match[2]))
-		}
-
+		return false, fmt.Errorf("schema attribute %q can not be set from an annotation", ann.key)
+	}
+	if pointer {
+		ptr := reflect.New(fvType)
+		ptr.Elem().Set(value)
+		value = ptr
 	}
-	updatedSchema := rfValue.Interface().(openapi3.Schema)
-	fieldSchema.Value = &updatedSchema
+	fv.Set(value)
+	fieldSchema.Ref = ""
+	fieldSchema.Value = schema
 
-	return false
+	return false, nil
 }
 
-func resolveField(schemas openapi3.Schemas, f *ast.Field, typ ast.Expr, declarationMap map[string]*ast.TypeSpec) (*openapi3.SchemaRef, bool) {
-	defer func() {
-		if r := recover(); r != nil {
-			fieldName := "<anonymous>"
-			if len(f.Names) > 0 {
-				fieldName = f.Names[0].Name
-			}
-			panic(fmt.Sprintf("resolveField panic on field %q (initial type %T, current type %T): %v", fieldName, f.Type, typ, r))
-		}
-	}()
-	// TODO add option to parse pointers as non optional
-	required := true
-	var fieldSchema *openapi3.SchemaRef
-	switch ft := typ.(type) {
-	case *ast.MapType:
-		// TODO is this default required correct ?
-		return openapi3.NewSchemaRef("", &openapi3.Schema{
-			Type: &openapi3.Types{"object"},
-		}), false
-	// TODO improve, we cannot handle array of arrays now
-	case *ast.ArrayType:
-		el := ft.Elt
-		switch at := ft.Elt.(type) {
-		case *ast.StarExpr:
-			el = at.X
-		}
-		ident := el.(*ast.Ident)
-		Type := &openapi3.Types{resolvePrimitiveType(ident.Name)}
-
-		//This is to prevent infinite recursion of array models
-		_, ok := declarationMap[ident.Name]
-		if ok {
-			fieldSchema = openapi3.NewSchemaRef(createRef(ident.Name), nil)
-		} else {
-			fieldSchema = openapi3.NewSchemaRef("", &openapi3.Schema{
-				Type: Type,
-			})
-		}
-		return openapi3.NewSchemaRef("", &openapi3.Schema{
-			Items: fieldSchema,
-			Type:  &openapi3.Types{"array"},
-		}), false
-	// TODO add option to parse pointers as non optional
-	case *ast.StarExpr:
-		required = false
-		typ = ft.X
-	// TODO parse packages
-	case *ast.SelectorExpr:
-		return openapi3.NewSchemaRef("", &openapi3.Schema{
-			Type: &openapi3.Types{"object"},
-		}), false
-	}
-
-	// *pkg.Type: StarExpr unwraps to SelectorExpr — handle it like a direct SelectorExpr.
-	if _, ok := typ.(*ast.SelectorExpr); ok {
-		return openapi3.NewSchemaRef("", &openapi3.Schema{
-			Type: &openapi3.Types{"object"},
-		}), required
-	}
-
-	ident := typ.(*ast.Ident)
-
-	Type := resolvePrimitiveType(ident.Name)
-
-	if Type == "object" {
-		doc := ""
-		if f.Doc != nil {
-			doc = f.Doc.Text()
-		}
-		if ident.Obj != nil && ident.Obj.Decl != nil {
-			name, subSchema := resolveSchema(schemas, ident.Obj.Decl.(*ast.TypeSpec), doc, declarationMap)
-			if name != nil {
-				if _, exists := schemas[*name]; !exists {
-					schemas[*name] = openapi3.NewSchemaRef("", &subSchema)
-				}
-				fieldSchema = openapi3.NewSchemaRef(createRef(*name), nil)
-			} else {
-				fieldSchema = openapi3.NewSchemaRef("", &subSchema)
-			}
-		} else {
-			decl, ok := declarationMap[ident.Name]
-			if ok {
-				name, subSchema := resolveSchema(schemas, decl, doc, declarationMap)
-				if name != nil {
-					if _, exists := schemas[*name]; !exists {
-						schemas[*name] = openapi3.NewSchemaRef("", &subSchema)
-					}
-					fieldSchema = openapi3.NewSchemaRef(createRef(*name), nil)
-				} else {
-					fieldSchema = openapi3.NewSchemaRef("", &subSchema)
-				}
-			} else {
-				fieldSchema = openapi3.NewSchemaRef("", &openapi3.Schema{
-					Type: &openapi3.Types{Type},
-				})
-			}
+// schemaAttribute returns the settable field of schema named attrName, either
+// by its json name, like maxLength, or by its Go name, like MaxLength.
+func schemaAttribute(schema reflect.Value, attrName string) (reflect.Value, bool) {
+	schemaType := schema.Type()
+	goName := strings.ToUpper(attrName[:1]) + attrName[1:]
+	for i := 0; i < schemaType.NumField(); i++ {
+		field := schemaType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == attrName || field.Name == goName {
+			return schema.Field(i), true
 		}
-	} else {
-		fieldSchema = openapi3.NewSchemaRef("", &openapi3.Schema{
-			Type: &openapi3.Types{Type},
-		})
 	}
 
-	return fieldSchema, required
+	return reflect.Value{}, false
 }
 
-func resolvePrimitive(f *ast.Field) (string, *openapi3.Schema) {
-	typ := f.Type.(*ast.Ident)
-	schema := openapi3.Schema{
-		Type: &openapi3.Types{resolvePrimitiveType(typ.Name)},
-	}
-
-	if f.Tag != nil {
+// basicSchema returns the schema of a basic type. Integers and floats carry
+// the format matching their size, unsigned integers have a minimum of 0.
+func basicSchema(t *types.Basic) *openapi3.Schema {
+	switch t.Kind() {
+	case types.Bool, types.UntypedBool:
+		return openapi3.NewBoolSchema()
+	case types.String, types.UntypedString:
+		return openapi3.NewStringSchema()
+	case types.Int8, types.Int16, types.Int32, types.UntypedRune:
+		return openapi3.NewInt32Schema()
+	case types.Int, types.Int64, types.UntypedInt:
+		return openapi3.NewInt64Schema()
+	case types.Uint8, types.Uint16:
+		return openapi3.NewInt32Schema().WithMin(0)
+	// uint64 values beyond the int64 range can not be described by a format
+	case types.Uint, types.Uint32, types.Uint64, types.Uintptr:
+		return openapi3.NewInt64Schema().WithMin(0)
+	case types.Float32:
+		return openapi3.NewFloat64Schema().WithFormat("float")
+	case types.Float64, types.UntypedFloat:
+		return openapi3.NewFloat64Schema().WithFormat("double")
 	}
 
-	return f.Names[0].Name, &schema
+	return openapi3.NewObjectSchema()
 }
 
-func resolvePrimitiveType(typ string) string {
-	switch typ {
-	case "int64", "int32", "int":
-		return "integer"
-	case "float64", "float32", "float":
-		return "number"
-	case "string", "byte":
-		return "string"
-	case "bool":
-		return "boolean"
-	// TODO parse type alias better
-	default:
-		return "object"
+// isByteSlice reports whether typ is a slice of bytes, which encoding/json
+// encodes as a base64 string.
+func isByteSlice(typ types.Type) bool {
+	slice, ok := typ.Underlying().(*types.Slice)
+	if !ok {
+		return false
 	}
+	elem, ok := slice.Elem().Underlying().(*types.Basic)
+	return ok && elem.Kind() == types.Uint8
 }
 
 func createRef(typeName string) string {
 	return fmt.Sprintf("#/components/schemas/%s", typeName)
 }
-
-var (
-	matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
-	matchAllCap   = regexp.MustCompile("([a-z0-9])([A-Z])")
-)
-
-func toSnakeUpperCase(str string) string {
-	snake := matchAllCap.ReplaceAllString(str, "${1}_${2}")
-	return snake
-}
@@ -1,45 +1,99 @@
 package openapi3Struct
 
 import (
+	"encoding/json"
 	"go/ast"
+	"go/importer"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
 )
 
-// parseTypeSpec parses a single Go source file and returns the TypeSpec and
-// declaration map for use with resolveSchema.
-func parseTypeSpec(t *testing.T, src string) (*ast.TypeSpec, map[string]*ast.TypeSpec) {
+// checkTestPackage parses and type checks Go source as a single package.
+// The source may import the given packages besides the standard library.
+func checkTestPackage(t *testing.T, path, src string, imports ...*packages.Package) *packages.Package {
 	t.Helper()
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
+	f, err := parser.ParseFile(fset, path+".go", src, parser.ParseComments)
 	if err != nil {
 		t.Fatalf("parse source: %v", err)
 	}
 
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
+	imported := map[string]*packages.Package{}
+	for _, pkg := range imports {
+		imported[pkg.PkgPath] = pkg
+	}
+	info := &types.Info{
+		Types:     map[ast.Expr]types.TypeAndValue{},
+		Defs:      map[*ast.Ident]types.Object{},
+		Uses:      map[*ast.Ident]types.Object{},
+		Instances: map[*ast.Ident]types.Instance{},
+	}
+	conf := types.Config{Importer: testImporter(imported)}
+	pkg, err := conf.Check(path, fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatalf("type check source: %v", err)
+	}
+
+	return &packages.Package{
+		ID:        path,
+		Name:      pkg.Name(),
+		PkgPath:   path,
+		Fset:      fset,
+		Syntax:    []*ast.File{f},
+		Types:     pkg,
+		TypesInfo: info,
+		Imports:   imported,
+		Module:    &packages.Module{Path: path},
+	}
+}
+
+// testImporter imports the given packages and falls back to the standard library.
+type testImporter map[string]*packages.Package
+
+func (i testImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := i[path]; ok {
+		return pkg.Types, nil
+	}
+	return importer.Default().Import(path)
+}
+
+// parseTypeDecl type checks a single Go source file and returns a resolver for
+// it together with the declaration of the named type, or of the last declared
+// type when name is empty.
+func parseTypeDecl(t *testing.T, src, name string) (*schemaResolver, *typeDecl) {
+	t.Helper()
+	pkg := checkTestPackage(t, "test", src)
+	r := newSchemaResolver([]*packages.Package{pkg}, schemaOptions{})
+
+	var target *typeDecl
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
 			if !ok {
 				continue
 			}
-			declMap[ts.Name.Name] = ts
-			// Use the last type as the target (the one we want to test).
-			target = ts
+			for _, spec := range gd.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if name == "" || ts.Name.Name == name {
+					target = r.decls[pkg.TypesInfo.Defs[ts.Name].(*types.TypeName)]
+				}
+			}
 		}
 	}
 	if target == nil {
 		t.Fatal("no type declaration found in source")
 	}
-	return target, declMap
+	return r, target
 }
 
 func TestResolveSchema_StructType(t *testing.T) {
@@ -51,10 +105,9 @@ type Foo struct {
 	Age  int    ` + "`json:\"age\"`" + `
 }
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "Foo" {
 		t.Fatalf("expected name 'Foo', got %v", name)
@@ -76,10 +129,9 @@ func TestResolveSchema_ArrayOfPrimitive(t *testing.T) {
 	src := `package test
 type StringList []string
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "StringList" {
 		t.Fatalf("expected name 'StringList', got %v", name)
@@ -104,36 +156,9 @@ type Item struct {
 }
 type ItemList []Item
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			if ts.Name.Name == "ItemList" {
-				target = ts
-			}
-		}
-	}
-	if target == nil {
-		t.Fatal("ItemList type not found")
-	}
+	r, decl := parseTypeDecl(t, src, "ItemList")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "ItemList" {
 		t.Fatalf("expected name 'ItemList', got %v", name)
@@ -144,7 +169,7 @@ type ItemList []Item
 	if schema.Items == nil {
 		t.Fatal("expected items schema")
 	}
-	// Item is in declarationMap, so it should be a $ref.
+	// Item is a declared type, so it should be a $ref.
 	if schema.Items.Ref != "#/components/schemas/Item" {
 		t.Fatalf("expected items ref '#/components/schemas/Item', got %q", schema.Items.Ref)
 	}
@@ -159,36 +184,9 @@ type Item struct {
 }
 type ItemPtrList []*Item
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			if ts.Name.Name == "ItemPtrList" {
-				target = ts
-			}
-		}
-	}
-	if target == nil {
-		t.Fatal("ItemPtrList type not found")
-	}
+	r, decl := parseTypeDecl(t, src, "ItemPtrList")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "ItemPtrList" {
 		t.Fatalf("expected name 'ItemPtrList', got %v", name)
@@ -210,10 +208,9 @@ func TestResolveSchema_ArrayOfInt(t *testing.T) {
 	src := `package test
 type IntList []int
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "IntList" {
 		t.Fatalf("expected name 'IntList', got %v", name)
@@ -235,10 +232,9 @@ func TestResolveSchema_FuncType_ReturnsEmptySchema(t *testing.T) {
 	src := `package test
 type Handler func(s string) error
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	// FuncType is skipped — returns nil name and empty schema.
 	if name != nil {
@@ -255,10 +251,9 @@ func TestResolveSchema_TypeDefinition_Primitive(t *testing.T) {
 	src := `package test
 type MyString string
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "MyString" {
 		t.Fatalf("expected name 'MyString', got %v", name)
@@ -274,10 +269,9 @@ func TestResolveSchema_TypeAlias_Primitive(t *testing.T) {
 	src := `package test
 type stringType = string
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	// Type aliases should not produce a named schema.
 	if name != nil {
@@ -294,10 +288,9 @@ func TestResolveSchema_TypeDefinition_Int(t *testing.T) {
 	src := `package test
 type MyInt int64
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "MyInt" {
 		t.Fatalf("expected name 'MyInt', got %v", name)
@@ -313,10 +306,9 @@ func TestResolveSchema_MapType(t *testing.T) {
 	src := `package test
 type MyMap map[string]string
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "MyMap" {
 		t.Fatalf("expected name 'MyMap', got %v", name)
@@ -332,10 +324,9 @@ func TestResolveSchema_InterfaceType(t *testing.T) {
 	src := `package test
 type MyInterface interface{}
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "MyInterface" {
 		t.Fatalf("expected name 'MyInterface', got %v", name)
@@ -352,34 +343,9 @@ func TestResolveSchema_ArrayOfSelectorExpr(t *testing.T) {
 import "time"
 type TimeList []time.Time
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			target = ts
-		}
-	}
-	if target == nil {
-		t.Fatal("no type declaration found")
-	}
+	r, decl := parseTypeDecl(t, src, "")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "TimeList" {
 		t.Fatalf("expected name 'TimeList', got %v", name)
@@ -390,8 +356,8 @@ type TimeList []time.Time
 	if schema.Items == nil {
 		t.Fatal("expected items schema")
 	}
-	if schema.Items.Value == nil || (*schema.Items.Value.Type)[0] != "object" {
-		t.Fatalf("expected items type 'object' for external type, got %v", schema.Items.Value.Type)
+	if schema.Items.Value == nil || (*schema.Items.Value.Type)[0] != "string" || schema.Items.Value.Format != "date-time" {
+		t.Fatalf("expected items type 'string' with format 'date-time' for time.Time, got %v", schema.Items.Value.Type)
 	}
 }
 
@@ -402,34 +368,9 @@ func TestResolveSchema_ArrayOfPointerSelectorExpr(t *testing.T) {
 import "time"
 type TimePtrList []*time.Time
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			target = ts
-		}
-	}
-	if target == nil {
-		t.Fatal("no type declaration found")
-	}
+	r, decl := parseTypeDecl(t, src, "")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "TimePtrList" {
 		t.Fatalf("expected name 'TimePtrList', got %v", name)
@@ -440,8 +381,8 @@ type TimePtrList []*time.Time
 	if schema.Items == nil {
 		t.Fatal("expected items schema")
 	}
-	if schema.Items.Value == nil || (*schema.Items.Value.Type)[0] != "object" {
-		t.Fatalf("expected items type 'object' for external pointer type, got %v", schema.Items.Value.Type)
+	if schema.Items.Value == nil || (*schema.Items.Value.Type)[0] != "string" || schema.Items.Value.Format != "date-time" {
+		t.Fatalf("expected items type 'string' with format 'date-time' for *time.Time, got %v", schema.Items.Value.Type)
 	}
 }
 
@@ -451,10 +392,9 @@ func TestResolveSchema_ArrayOfMap(t *testing.T) {
 	src := `package test
 type MapList []map[string]string
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "MapList" {
 		t.Fatalf("expected name 'MapList', got %v", name)
@@ -471,7 +411,7 @@ type MapList []map[string]string
 }
 
 // TestResolveField_CrossPackagePointerField tests that a field typed *pkg.Type
-// (StarExpr wrapping SelectorExpr) does not panic and resolves as an optional object.
+// (StarExpr wrapping SelectorExpr) does not panic and resolves as an optional field.
 // Regression test for the StarExpr→SelectorExpr fall-through panic.
 func TestResolveField_CrossPackagePointerField_DoesNotPanic(t *testing.T) {
 	t.Parallel()
@@ -482,34 +422,9 @@ type Outer struct {
 	Timestamp *time.Time ` + "`json:\"timestamp,omitempty\"`" + `
 }
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			target = ts
-		}
-	}
-	if target == nil {
-		t.Fatal("no type declaration found")
-	}
+	r, decl := parseTypeDecl(t, src, "")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "Outer" {
 		t.Fatalf("expected name 'Outer', got %v", name)
@@ -518,8 +433,8 @@ type Outer struct {
 	if !ok {
 		t.Fatal("expected property 'timestamp'")
 	}
-	if prop.Value == nil || (*prop.Value.Type)[0] != "object" {
-		t.Fatalf("expected timestamp type 'object', got %v", prop.Value.Type)
+	if prop.Value == nil || (*prop.Value.Type)[0] != "string" || prop.Value.Format != "date-time" {
+		t.Fatalf("expected timestamp type 'string' with format 'date-time', got %v", prop.Value.Type)
 	}
 	// Pointer field must not be required.
 	for _, req := range schema.Required {
@@ -530,8 +445,8 @@ type Outer struct {
 }
 
 // TestResolveField_CrossPackageDirectField tests that a field typed pkg.Type
-// (direct SelectorExpr, non-pointer) resolves as an object without panicking.
-func TestResolveField_CrossPackageDirectField_ReturnsObject(t *testing.T) {
+// (direct SelectorExpr, non-pointer) resolves to its mapped schema without panicking.
+func TestResolveField_CrossPackageDirectField_ReturnsMappedSchema(t *testing.T) {
 	t.Parallel()
 
 	src := `package test
@@ -540,34 +455,9 @@ type Outer struct {
 	Timestamp time.Time ` + "`json:\"timestamp\"`" + `
 }
 `
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	if err != nil {
-		t.Fatalf("parse source: %v", err)
-	}
-
-	declMap := map[string]*ast.TypeSpec{}
-	var target *ast.TypeSpec
-	for _, decl := range f.Decls {
-		gd, ok := decl.(*ast.GenDecl)
-		if !ok {
-			continue
-		}
-		for _, spec := range gd.Specs {
-			ts, ok := spec.(*ast.TypeSpec)
-			if !ok {
-				continue
-			}
-			declMap[ts.Name.Name] = ts
-			target = ts
-		}
-	}
-	if target == nil {
-		t.Fatal("no type declaration found")
-	}
+	r, decl := parseTypeDecl(t, src, "")
 
-	schemas := openapi3.Schemas{}
-	name, schema := resolveSchema(schemas, target, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "Outer" {
 		t.Fatalf("expected name 'Outer', got %v", name)
@@ -576,8 +466,8 @@ type Outer struct {
 	if !ok {
 		t.Fatal("expected property 'timestamp'")
 	}
-	if prop.Value == nil || (*prop.Value.Type)[0] != "object" {
-		t.Fatalf("expected timestamp type 'object', got %v", prop.Value.Type)
+	if prop.Value == nil || (*prop.Value.Type)[0] != "string" || prop.Value.Format != "date-time" {
+		t.Fatalf("expected timestamp type 'string' with format 'date-time', got %v", prop.Value.Type)
 	}
 }
 
@@ -595,11 +485,10 @@ type Outer struct {
 	Sub Inner ` + "`json:\"sub\"`" + `
 }
 `
-	// parseTypeSpec returns the last type (Outer) as target with both in declMap.
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	// parseTypeDecl returns the last type (Outer) as target.
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "Outer" {
 		t.Fatalf("expected name 'Outer', got %v", name)
@@ -612,7 +501,7 @@ type Outer struct {
 		t.Fatalf("expected sub to be $ref to Inner, got %q", sub.Ref)
 	}
 	// Inner must be auto-registered so the $ref is resolvable.
-	if _, ok := schemas["Inner"]; !ok {
+	if _, ok := r.schemas["Inner"]; !ok {
 		t.Fatal("expected Inner to be auto-registered in schemas map")
 	}
 }
@@ -630,10 +519,9 @@ type Outer struct {
 	Sub *Inner ` + "`json:\"sub,omitempty\"`" + `
 }
 `
-	ts, declMap := parseTypeSpec(t, src)
-	schemas := openapi3.Schemas{}
+	r, decl := parseTypeDecl(t, src, "")
 
-	name, schema := resolveSchema(schemas, ts, "", declMap)
+	name, schema := r.resolveSchema(decl)
 
 	if name == nil || *name != "Outer" {
 		t.Fatalf("expected name 'Outer', got %v", name)
@@ -645,7 +533,7 @@ type Outer struct {
 	if sub.Ref != "#/components/schemas/Inner" {
 		t.Fatalf("expected sub to be $ref to Inner, got %q", sub.Ref)
 	}
-	if _, ok := schemas["Inner"]; !ok {
+	if _, ok := r.schemas["Inner"]; !ok {
 		t.Fatal("expected Inner to be auto-registered in schemas map")
 	}
 	// Pointer field must be optional.
@@ -655,3 +543,1276 @@ type Outer struct {
 		}
 	}
 }
+
+// TestResolveSchema_SameNameInDifferentPackages tests that declarations are
+// resolved through the type checker, so a type named like one from another
+// package resolves to its own declaration.
+func TestResolveSchema_SameNameInDifferentPackages(t *testing.T) {
+	t.Parallel()
+
+	a := checkTestPackage(t, "example.com/a", `package a
+type Address struct {
+	Street string `+"`json:\"street\"`"+`
+}
+type User struct {
+	Address Address `+"`json:\"address\"`"+`
+}
+`)
+	b := checkTestPackage(t, "example.com/b", `package b
+type Address struct {
+	Zip string `+"`json:\"zip\"`"+`
+}
+`)
+	r := newSchemaResolver([]*packages.Package{b, a}, schemaOptions{})
+	user := r.decls[a.Types.Scope().Lookup("User").(*types.TypeName)]
+
+	_, schema := r.resolveSchema(user)
+
+	if schema.Properties["address"].Ref != "#/components/schemas/Address" {
+		t.Fatalf("expected address to be $ref to Address, got %q", schema.Properties["address"].Ref)
+	}
+	address, ok := r.schemas["Address"]
+	if !ok {
+		t.Fatal("expected Address to be auto-registered in schemas map")
+	}
+	if _, ok := address.Value.Properties["street"]; !ok {
+		t.Fatalf("expected Address from package a, got properties %v", address.Value.Properties)
+	}
+}
+
+func TestResolveSchema_WellKnownTypes(t *testing.T) {
+	t.Parallel()
+
+	uuid := checkTestPackage(t, "github.com/google/uuid", `package uuid
+type UUID [16]byte
+`)
+	pkg := checkTestPackage(t, "test", `package test
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+type Event struct {
+	ID      uuid.UUID       `+"`json:\"id\"`"+`
+	Timeout time.Duration   `+"`json:\"timeout\"`"+`
+	Payload json.RawMessage `+"`json:\"payload\"`"+`
+	Source  net.IP          `+"`json:\"source\"`"+`
+}
+`, uuid)
+	r := newSchemaResolver([]*packages.Package{pkg}, schemaOptions{})
+	event := r.decls[pkg.Types.Scope().Lookup("Event").(*types.TypeName)]
+
+	_, schema := r.resolveSchema(event)
+
+	expected := map[string][2]string{
+		"id":      {"string", "uuid"},
+		"timeout": {"integer", "int64"},
+		"source":  {"string", "ip"},
+	}
+	for name, typeFormat := range expected {
+		prop := schema.Properties[name]
+		if prop == nil || prop.Value == nil || !prop.Value.Type.Is(typeFormat[0]) || prop.Value.Format != typeFormat[1] {
+			t.Errorf("expected %s to be %s/%s, got %v", name, typeFormat[0], typeFormat[1], prop)
+		}
+	}
+	if payload := schema.Properties["payload"]; payload == nil || payload.Value == nil || payload.Value.Type != nil {
+		t.Errorf("expected payload to be an untyped schema, got %v", payload)
+	}
+	if _, ok := r.schemas["UUID"]; ok {
+		t.Error("expected mapped type UUID not to generate a component")
+	}
+}
+
+func TestResolveSchema_CustomTypeMapping(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+import "time"
+type Money struct {
+	Units int64 ` + "`json:\"units\"`" + `
+}
+type Order struct {
+	Total   Money     ` + "`json:\"total\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+`
+	pkg := checkTestPackage(t, "test", src)
+	r := newSchemaResolver([]*packages.Package{pkg}, schemaOptions{
+		typeMappings: map[string]*openapi3.Schema{
+			"test.Money": openapi3.NewStringSchema().WithFormat("decimal"),
+			"time.Time":  openapi3.NewInt64Schema(),
+		},
+	})
+	order := r.decls[pkg.Types.Scope().Lookup("Order").(*types.TypeName)]
+
+	_, schema := r.resolveSchema(order)
+
+	total := schema.Properties["total"]
+	if total.Ref != "" || !total.Value.Type.Is("string") || total.Value.Format != "decimal" {
+		t.Fatalf("expected total to use the custom mapping, got %v", total)
+	}
+	if created := schema.Properties["created"]; !created.Value.Type.Is("integer") {
+		t.Fatalf("expected custom mapping to override the built-in one, got %v", created.Value.Type)
+	}
+	if _, ok := r.schemas["Money"]; ok {
+		t.Error("expected mapped type Money not to generate a component")
+	}
+}
+
+func TestWalkPackage_GenericInstantiations(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+// oapi:schema
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+	Total int ` + "`json:\"total\"`" + `
+}
+// oapi:schema
+type Envelope[K comparable, V any] struct {
+	Data map[K]V ` + "`json:\"data\"`" + `
+	Meta *V      ` + "`json:\"meta\"`" + `
+}
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+// oapi:schema
+type Dashboard struct {
+	Users  Page[User]                ` + "`json:\"users\"`" + `
+	Labels Page[string]              ` + "`json:\"labels\"`" + `
+	Tagged Envelope[string, Page[User]] ` + "`json:\"tagged\"`" + `
+}
+// oapi:schema
+type UserPage Page[User]
+`
+	pkg := checkTestPackage(t, "test", src)
+	schemas, _ := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{})
+
+	if _, ok := schemas["Page"]; ok {
+		t.Error("expected uninstantiated generic type not to generate a component")
+	}
+	dashboard := schemas["Dashboard"].Value
+	if ref := dashboard.Properties["users"].Ref; ref != "#/components/schemas/PageUser" {
+		t.Fatalf("expected users to be $ref to PageUser, got %q", ref)
+	}
+	if ref := dashboard.Properties["labels"].Ref; ref != "#/components/schemas/PageString" {
+		t.Fatalf("expected labels to be $ref to PageString, got %q", ref)
+	}
+	if ref := dashboard.Properties["tagged"].Ref; ref != "#/components/schemas/EnvelopeStringPageUser" {
+		t.Fatalf("expected tagged to be $ref to EnvelopeStringPageUser, got %q", ref)
+	}
+
+	pageUser := schemas["PageUser"].Value
+	if ref := pageUser.Properties["items"].Value.Items.Ref; ref != "#/components/schemas/User" {
+		t.Fatalf("expected PageUser items to be $ref to User, got %q", ref)
+	}
+	pageString := schemas["PageString"].Value
+	if items := pageString.Properties["items"].Value.Items.Value; !items.Type.Is("string") {
+		t.Fatalf("expected PageString items to be strings, got %v", items.Type)
+	}
+	envelope := schemas["EnvelopeStringPageUser"].Value
+	if ref := envelope.Properties["meta"].Ref; ref != "#/components/schemas/PageUser" {
+		t.Fatalf("expected Envelope meta to be $ref to PageUser, got %q", ref)
+	}
+
+	userPage, ok := schemas["UserPage"]
+	if !ok {
+		t.Fatal("expected UserPage schema")
+	}
+	if ref := userPage.Value.Properties["items"].Value.Items.Ref; ref != "#/components/schemas/User" {
+		t.Fatalf("expected UserPage items to be $ref to User, got %q", ref)
+	}
+}
+
+func TestWalkPackage_GenericNamer(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+// oapi:schema
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+}
+type User struct{}
+var _ Page[User]
+`
+	pkg := checkTestPackage(t, "test", src)
+	schemas, _ := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{
+		genericNamer: func(name string, typeArgs []string) string {
+			return strings.Join(typeArgs, "") + name
+		},
+	})
+
+	if _, ok := schemas["UserPage"]; !ok {
+		t.Fatalf("expected instantiation found in the package to be named UserPage, got %v", schemas)
+	}
+}
+
+func TestResolveSchema_EnumFromConstBlock(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+// oapi:schema
+type Status string
+
+const (
+	// StatusActive is an active account.
+	StatusActive Status = "active"
+	StatusBlocked Status = "blocked" // blocked by an admin
+	StatusDefault = StatusActive
+	unrelated = "other"
+)
+`
+	r, decl := parseTypeDecl(t, src, "Status")
+
+	_, schema := r.resolveSchema(decl)
+
+	if len(schema.Enum) != 2 || schema.Enum[0] != "active" || schema.Enum[1] != "blocked" {
+		t.Fatalf("expected enum [active blocked], got %v", schema.Enum)
+	}
+	varNames := schema.Extensions["x-enum-varnames"].([]string)
+	if len(varNames) != 2 || varNames[0] != "StatusActive" || varNames[1] != "StatusBlocked" {
+		t.Fatalf("expected x-enum-varnames [StatusActive StatusBlocked], got %v", varNames)
+	}
+	descriptions := schema.Extensions["x-enum-descriptions"].([]string)
+	if descriptions[0] != "StatusActive is an active account." || descriptions[1] != "blocked by an admin" {
+		t.Fatalf("unexpected x-enum-descriptions %q", descriptions)
+	}
+}
+
+func TestResolveSchema_EnumFromIota(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityMedium
+	PriorityHigh
+)
+
+type Task struct {
+	Priority Priority ` + "`json:\"priority\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "Task")
+
+	_, schema := r.resolveSchema(decl)
+
+	if ref := schema.Properties["priority"].Ref; ref != "#/components/schemas/Priority" {
+		t.Fatalf("expected priority to be $ref to Priority, got %q", ref)
+	}
+	priority := r.schemas["Priority"].Value
+	if !priority.Type.Is("integer") {
+		t.Fatalf("expected Priority type 'integer', got %v", priority.Type)
+	}
+	if len(priority.Enum) != 3 || priority.Enum[0] != int64(0) || priority.Enum[2] != int64(2) {
+		t.Fatalf("expected enum [0 1 2], got %v", priority.Enum)
+	}
+	if _, ok := priority.Extensions["x-enum-descriptions"]; ok {
+		t.Error("expected no x-enum-descriptions without const docs")
+	}
+}
+
+func TestResolveSchema_JSONTagOptions(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Account struct {
+	Name     string  ` + "`json:\"name\"`" + `
+	Nickname string  ` + "`json:\"nickname,omitempty\"`" + `
+	Settings Settings ` + "`json:\"settings,omitzero\"`" + `
+	Balance  int64   ` + "`json:\"balance,string\"`" + `
+	Active   *bool   ` + "`json:\"active,string\"`" + `
+	Label    string  ` + "`json:\"label,string\"`" + `
+	Password string  ` + "`json:\"-\"`" + `
+	Dash     string  ` + "`json:\"-,\"`" + `
+	Untagged string  ` + "`json:\",omitempty\"`" + `
+	Forced   string  ` + "`json:\"forced,omitempty\" oapi_required:\"true\"`" + `
+	secret   string
+}
+type Settings struct{}
+`
+	r, decl := parseTypeDecl(t, src, "Account")
+
+	_, schema := r.resolveSchema(decl)
+
+	for _, name := range []string{"password", "Password", "secret"} {
+		if _, ok := schema.Properties[name]; ok {
+			t.Errorf("expected property %q to be skipped", name)
+		}
+	}
+	for _, name := range []string{"-", "Untagged"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("expected property %q", name)
+		}
+	}
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for name, expected := range map[string]bool{
+		"name":     true,
+		"nickname": false,
+		"settings": false,
+		"Untagged": false,
+		"forced":   true,
+	} {
+		if required[name] != expected {
+			t.Errorf("expected %q required=%v, got %v", name, expected, required[name])
+		}
+	}
+	for _, name := range []string{"balance", "active", "label"} {
+		prop := schema.Properties[name]
+		if prop.Value == nil || !prop.Value.Type.Is("string") {
+			t.Errorf("expected %q to be a string, got %v", name, prop.Value)
+		}
+	}
+}
+
+func TestResolveSchema_UnexportedEmbeddedStruct(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type base struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+type name string
+type Account struct {
+	base
+	name
+}
+`
+	r, decl := parseTypeDecl(t, src, "Account")
+
+	_, schema := r.resolveSchema(decl)
+
+	if len(schema.AllOf) != 1 || schema.AllOf[0].Ref != "#/components/schemas/base" {
+		t.Fatalf("expected only the unexported embedded struct to be kept, got %v", schema.AllOf)
+	}
+}
+
+func TestResolveSchema_RequiredStrategies(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Inner struct{}
+type Outer struct {
+	Name     string   ` + "`json:\"name\"`" + `
+	Nickname *string  ` + "`json:\"nickname\"`" + `
+	Inner    *Inner   ` + "`json:\"inner\"`" + `
+	Tags     []string ` + "`json:\"tags\" validate:\"required,min=1\"`" + `
+	Note     *string  ` + "`json:\"note,omitempty\"`" + `
+	Forced   *string  ` + "`json:\"forced,omitempty\" oapi_required:\"true\"`" + `
+}
+`
+	tests := []struct {
+		strategy RequiredStrategy
+		required []string
+		nullable []string
+	}{
+		{RequiredByType, []string{"name", "tags", "forced"}, nil},
+		{RequiredPointerNullable, []string{"name", "nickname", "inner", "tags", "note", "forced"}, []string{"nickname", "inner", "note", "forced"}},
+		{RequiredExplicit, []string{"forced"}, []string{"nickname", "inner"}},
+		{RequiredOmitEmpty, []string{"name", "nickname", "inner", "tags", "forced"}, []string{"nickname", "inner"}},
+		{RequiredValidator, []string{"tags", "forced"}, []string{"nickname", "inner"}},
+	}
+	for _, tt := range tests {
+		r, decl := parseTypeDecl(t, src, "Outer")
+		r.opts.requiredStrategy = tt.strategy
+
+		_, schema := r.resolveSchema(decl)
+
+		if strings.Join(schema.Required, ",") != strings.Join(tt.required, ",") {
+			t.Errorf("strategy %d: expected required %v, got %v", tt.strategy, tt.required, schema.Required)
+		}
+		nullable := []string{}
+		for _, name := range []string{"name", "nickname", "inner", "tags", "note", "forced"} {
+			prop := schema.Properties[name]
+			if prop.Value != nil && prop.Value.Nullable {
+				nullable = append(nullable, name)
+			}
+		}
+		if strings.Join(nullable, ",") != strings.Join(tt.nullable, ",") {
+			t.Errorf("strategy %d: expected nullable %v, got %v", tt.strategy, tt.nullable, nullable)
+		}
+	}
+}
+
+func TestResolveSchema_NullableOpenAPI31(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Inner struct{}
+type Outer struct {
+	Nickname *string ` + "`json:\"nickname\"`" + `
+	Inner    *Inner  ` + "`json:\"inner\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "Outer")
+	r.opts.requiredStrategy = RequiredOmitEmpty
+	r.opts.openapi31 = true
+
+	_, schema := r.resolveSchema(decl)
+
+	nickname := schema.Properties["nickname"].Value
+	if nickname.Nullable || !nickname.Type.Includes("string") || !nickname.Type.Includes("null") {
+		t.Fatalf("expected nickname type [string null], got %v", nickname.Type)
+	}
+	inner := schema.Properties["inner"].Value
+	if len(inner.AnyOf) != 2 || inner.AnyOf[0].Ref != "#/components/schemas/Inner" || !inner.AnyOf[1].Value.Type.Is("null") {
+		t.Fatalf("expected inner to be anyOf Inner and null, got %v", inner.AnyOf)
+	}
+}
+
+func TestResolveSchema_ValidateTag(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Role string
+type User struct {
+	Name    string            ` + "`json:\"name\" validate:\"required,min=1,max=64\"`" + `
+	Email   *string           ` + "`json:\"email\" validate:\"omitempty,email\"`" + `
+	Age     int               ` + "`json:\"age\" validate:\"gte=0,lt=150\"`" + `
+	Kind    string            ` + "`json:\"kind\" validate:\"oneof=admin member guest\"`" + `
+	Level   int               ` + "`json:\"level\" validate:\"oneof=1 2 3\"`" + `
+	Code    string            ` + "`json:\"code\" validate:\"len=4,alphanum\"`" + `
+	Tags    []string          ` + "`json:\"tags\" validate:\"min=1,unique,dive,max=10\"`" + `
+	Labels  map[string]string ` + "`json:\"labels\" validate:\"max=5\"`" + `
+	Role    Role              ` + "`json:\"role\" validate:\"required,startswith=r\"`" + `
+	Plain   Role              ` + "`json:\"plain\" validate:\"required\"`" + `
+	Either  string            ` + "`json:\"either\" validate:\"email|url\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "User")
+
+	_, schema := r.resolveSchema(decl)
+	props := schema.Properties
+
+	name := props["name"].Value
+	if name.MinLength != 1 || name.MaxLength == nil || *name.MaxLength != 64 {
+		t.Errorf("expected name length 1..64, got %d..%v", name.MinLength, name.MaxLength)
+	}
+	if email := props["email"].Value; email.Format != "email" {
+		t.Errorf("expected email format 'email', got %q", email.Format)
+	}
+	age := props["age"].Value
+	if age.Min == nil || *age.Min != 0 || age.ExclusiveMin || age.Max == nil || *age.Max != 150 || !age.ExclusiveMax {
+		t.Errorf("expected age 0 <= x < 150, got %v", age)
+	}
+	if kind := props["kind"].Value; len(kind.Enum) != 3 || kind.Enum[0] != "admin" {
+		t.Errorf("expected kind enum [admin member guest], got %v", kind.Enum)
+	}
+	if level := props["level"].Value; len(level.Enum) != 3 || level.Enum[0] != int64(1) {
+		t.Errorf("expected level enum [1 2 3], got %v", level.Enum)
+	}
+	code := props["code"].Value
+	if code.MinLength != 4 || code.MaxLength == nil || *code.MaxLength != 4 || code.Pattern != "^[a-zA-Z0-9]+$" {
+		t.Errorf("expected code of length 4 with alphanum pattern, got %v", code)
+	}
+	tags := props["tags"].Value
+	if tags.MinItems != 1 || !tags.UniqueItems {
+		t.Errorf("expected tags minItems 1 and uniqueItems, got %v", tags)
+	}
+	if items := tags.Items.Value; items.MaxLength == nil || *items.MaxLength != 10 {
+		t.Errorf("expected tags items maxLength 10 from dive, got %v", items)
+	}
+	if labels := props["labels"].Value; labels.MaxProps == nil || *labels.MaxProps != 5 {
+		t.Errorf("expected labels maxProperties 5, got %v", labels.MaxProps)
+	}
+	role := props["role"]
+	if role.Ref != "" || len(role.Value.AllOf) != 1 || role.Value.AllOf[0].Ref != "#/components/schemas/Role" || role.Value.Pattern != "^r" {
+		t.Errorf("expected role to wrap the Role $ref in allOf with a pattern, got %v", role.Value)
+	}
+	if plain := props["plain"]; plain.Ref != "#/components/schemas/Role" {
+		t.Errorf("expected plain to stay a $ref without constraints, got %v", plain)
+	}
+	if either := props["either"].Value; either.Format != "" {
+		t.Errorf("expected alternatives to be ignored, got format %q", either.Format)
+	}
+	required := strings.Join(schema.Required, ",")
+	if !strings.Contains(required, "name") || !strings.Contains(required, "role") {
+		t.Errorf("expected validate:\"required\" fields to be required, got %v", schema.Required)
+	}
+}
+
+func TestResolveSchema_DocDescriptions(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Address struct{}
+
+// User is a registered account. It can log in.
+//
+// oapi:schema
+type User struct {
+	// Name is the display name.
+	// oapi_maxLength:"64"
+	Name    string  ` + "`json:\"name\"`" + `
+	Email   string  ` + "`json:\"email\"`" + ` // primary email address
+	// Address is where invoices are sent.
+	Address Address ` + "`json:\"address\"`" + `
+	// oapi_required:"true"
+	Plain   string  ` + "`json:\"plain\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "User")
+	r.opts.docTitles = true
+
+	_, schema := r.resolveSchema(decl)
+
+	if schema.Description != "User is a registered account. It can log in." {
+		t.Fatalf("unexpected description %q", schema.Description)
+	}
+	if schema.Title != "User is a registered account." {
+		t.Fatalf("unexpected title %q", schema.Title)
+	}
+	name := schema.Properties["name"].Value
+	if name.Description != "Name is the display name." || name.MaxLength == nil || *name.MaxLength != 64 {
+		t.Fatalf("expected name description without annotation, got %q", name.Description)
+	}
+	if email := schema.Properties["email"].Value; email.Description != "primary email address" {
+		t.Fatalf("expected email description from line comment, got %q", email.Description)
+	}
+	address := schema.Properties["address"].Value
+	if address == nil || address.Description != "Address is where invoices are sent." || address.AllOf[0].Ref != "#/components/schemas/Address" {
+		t.Fatalf("expected address to wrap the Address $ref with a description, got %v", address)
+	}
+	if plain := schema.Properties["plain"].Value; plain.Description != "" {
+		t.Fatalf("expected no description from annotation only doc, got %q", plain.Description)
+	}
+}
+
+func TestResolveSchema_AnnotationValues(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Address struct{}
+
+// oapi:schema
+// oapi_discriminator:kind oapi_discriminator_mapped_parsed:oneOf
+type User struct {
+	Kind    string  ` + "`json:\"kind\"`" + `
+	Login   string  ` + "`json:\"login\" oapi_pattern:\"^[a-z]+\\\\d$\"`" + `
+	Email   string  ` + "`json:\"email\" oapi_example:\"foo@bar.com\" oapi_description:\"Hello, world.\"`" + `
+	Ratio   float64 ` + "`json:\"ratio\" oapi_minimum:\"0.5\" oapi_exclusiveMinimum:\"true\"`" + `
+	// oapi_pattern:"^[a-z]+\d$" oapi_maxLength:"8"
+	Code    string  ` + "`json:\"code\"`" + `
+	// oapi_description: Where invoices are sent, if any.
+	Address Address ` + "`json:\"address\" oapi_required:\"true\"`" + `
+	// Name of the user.
+	Name    string  ` + "`json:\"name\" oapi_description:\"explicit\"`" + `
+	// Nickname of the user.
+	// oapi_description: Shown next to comments.
+	Nick    string  ` + "`json:\"nick\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "User")
+
+	_, schema := r.resolveSchema(decl)
+	props := schema.Properties
+
+	if login := props["login"].Value; login.Pattern != `^[a-z]+\d$` {
+		t.Errorf("expected login pattern ^[a-z]+\\d$, got %q", login.Pattern)
+	}
+	email := props["email"].Value
+	if email.Example != "foo@bar.com" || email.Description != "Hello, world." {
+		t.Errorf("expected email example and description, got %q %q", email.Example, email.Description)
+	}
+	ratio := props["ratio"].Value
+	if ratio.Min == nil || *ratio.Min != 0.5 || !ratio.ExclusiveMin {
+		t.Errorf("expected ratio exclusive minimum 0.5, got %v", ratio.Min)
+	}
+	code := props["code"].Value
+	if code.Pattern != `^[a-z]+\d$` || code.MaxLength == nil || *code.MaxLength != 8 {
+		t.Errorf("expected code pattern and maxLength from doc annotations, got %q %v", code.Pattern, code.MaxLength)
+	}
+	address := props["address"]
+	if address.Ref != "" || address.Value.Description != "Where invoices are sent, if any." || address.Value.AllOf[0].Ref != "#/components/schemas/Address" {
+		t.Errorf("expected address to wrap the Address $ref with a description, got %v", address.Value)
+	}
+	if name := props["name"].Value; name.Description != "explicit" {
+		t.Errorf("expected the oapi_description tag to win over the doc comment, got %q", name.Description)
+	}
+	if nick := props["nick"].Value; nick.Description != "Shown next to comments." {
+		t.Errorf("expected the oapi_description annotation to win over the doc comment, got %q", nick.Description)
+	}
+	if !strings.Contains(strings.Join(schema.Required, ","), "address") {
+		t.Errorf("expected address to be required, got %v", schema.Required)
+	}
+	if schema.Discriminator == nil || schema.Discriminator.PropertyName != "kind" {
+		t.Errorf("expected discriminator on kind, got %v", schema.Discriminator)
+	}
+}
+
+func TestResolveSchema_Diagnostics(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+
+// oapi:schema
+// oapi_discriminator:"kind
+type User struct {
+	Age   int     ` + "`json:\"age\" oapi_minimum:\"abc\"`" + `
+	Name  string  ` + "`json:\"name\" oapi_foo:\"bar\"`" + `
+	// oapi_maxLength:"-1"
+	Login string  ` + "`json:\"login\"`" + `
+	Tag   string  ` + "`json:name`" + `
+	Phase complex128 ` + "`json:\"phase\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "User")
+
+	r.resolveSchema(decl)
+
+	expected := []string{
+		`test.go:4:4: error: malformed annotation: invalid value of annotation "oapi_discriminator": missing closing quote`,
+		`test.go:6:16: error: invalid value "abc" of oapi_minimum: expected a number`,
+		`test.go:7:16: error: unknown schema attribute "oapi_foo"`,
+		`test.go:8:5: error: invalid value "-1" of oapi_maxLength: expected a non-negative integer`,
+		`test.go:10:16: error: malformed struct tag of field Tag: malformed struct tag "json:name"`,
+		`test.go:11:2: warning: field Phase of type complex128 can not be encoded as JSON`,
+	}
+	if len(r.diagnostics) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %v", len(expected), r.diagnostics)
+	}
+	for i, diagnostic := range r.diagnostics {
+		if diagnostic.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], diagnostic.Error())
+		}
+	}
+}
+
+func TestWalkPackage_NamingStrategies(t *testing.T) {
+	t.Parallel()
+
+	a := checkTestPackage(t, "example.com/a/users", `package users
+type User struct {
+	Name string `+"`json:\"name\"`"+`
+}
+`)
+	b := checkTestPackage(t, "example.com/b/users", `package users
+type User struct {
+	Login string `+"`json:\"login\"`"+`
+}
+`)
+	c := checkTestPackage(t, "example.com/admin", `package admin
+type User struct {
+	Role string `+"`json:\"role\"`"+`
+}
+`)
+	api := checkTestPackage(t, "example.com/api", `package api
+import (
+	a "example.com/a/users"
+	b "example.com/b/users"
+	"example.com/admin"
+)
+
+// oapi:schema
+type Team struct {
+	Members []a.User   `+"`json:\"members\"`"+`
+	Guests  []b.User   `+"`json:\"guests\"`"+`
+	Admin   admin.User `+"`json:\"admin\"`"+`
+	Owner   *Owner     `+"`json:\"owner\"`"+`
+	Page    Page[a.User] `+"`json:\"page\"`"+`
+	Admins  Page[Page[Owner]] `+"`json:\"admins\"`"+`
+}
+
+type Page[T any] struct {
+	Items []T `+"`json:\"items\"`"+`
+}
+
+// Owner is renamed, whatever the strategy.
+//
+// oapi:schema oapi_name:"TeamOwner"
+type Owner struct{}
+`, a, b, c)
+	hashA := importPathHash("example.com/a/users")
+	hashB := importPathHash("example.com/b/users")
+
+	tests := []struct {
+		strategy NamingStrategy
+		members  string
+		guests   string
+		admin    string
+		page     string
+		admins   string
+	}{
+		{strategy: NamingPackageQualified, members: "users.User", guests: "users.User", admin: "admin.User", page: "api.PageUser", admins: "api.PagePageTeamOwner"},
+		{
+			strategy: NamingImportPathHash, members: "User_" + hashA, guests: "User_" + hashB, admin: "User_" + importPathHash("example.com/admin"),
+			page:   "PageUser_" + importPathHash("example.com/api.Page[example.com/a/users.User]"),
+			admins: "PagePageTeamOwner_" + importPathHash("example.com/api.Page[example.com/api.Page[example.com/api.Owner]]"),
+		},
+		{strategy: NamingAuto, members: "User_" + hashA, guests: "User_" + hashB, admin: "admin.User", page: "PageUser", admins: "PagePageTeamOwner"},
+	}
+	for _, test := range tests {
+		r := newSchemaResolver([]*packages.Package{api}, schemaOptions{namingStrategy: test.strategy})
+		for _, decl := range r.annotated {
+			r.component(decl, decl.obj.Type())
+		}
+		if test.strategy == NamingAuto {
+			r.disambiguate()
+		}
+
+		team, ok := r.schemas["Team"]
+		if test.strategy != NamingAuto {
+			team, ok = r.schemas[r.typeName(api.Types.Scope().Lookup("Team").(*types.TypeName), test.strategy)]
+		}
+		if !ok {
+			t.Fatalf("strategy %d: expected Team schema, got %v", test.strategy, r.schemas)
+		}
+		props := team.Value.Properties
+		for property, name := range map[string]string{"members": test.members, "guests": test.guests} {
+			if ref := props[property].Value.Items.Ref; ref != createRef(name) {
+				t.Errorf("strategy %d: expected %s items to be $ref to %s, got %q", test.strategy, property, name, ref)
+			}
+		}
+		if ref := props["admin"].Ref; ref != createRef(test.admin) {
+			t.Errorf("strategy %d: expected admin to be $ref to %s, got %q", test.strategy, test.admin, ref)
+		}
+		for property, name := range map[string]string{"page": test.page, "admins": test.admins} {
+			if ref := props[property].Ref; ref != createRef(name) {
+				t.Errorf("strategy %d: expected %s to be $ref to %s, got %q", test.strategy, property, name, ref)
+			}
+		}
+		if ref := props["owner"].Ref; ref != createRef("TeamOwner") {
+			t.Errorf("strategy %d: expected owner to be $ref to TeamOwner, got %q", test.strategy, ref)
+		}
+		for _, name := range []string{test.members, test.guests, test.admin, test.page, test.admins, "TeamOwner"} {
+			if _, ok := r.schemas[name]; !ok {
+				t.Errorf("strategy %d: expected component %s, got %v", test.strategy, name, r.schemas)
+			}
+		}
+		if strings.Contains(r.diagnostics.Error(), "already used") != (test.strategy == NamingPackageQualified) {
+			t.Errorf("strategy %d: unexpected diagnostics %v", test.strategy, r.diagnostics)
+		}
+	}
+}
+
+func TestResolveSchema_NumericFormats(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Blob []byte
+type Numbers struct {
+	Int     int       ` + "`json:\"int\"`" + `
+	Int8    int8      ` + "`json:\"int8\"`" + `
+	Int16   int16     ` + "`json:\"int16\"`" + `
+	Int32   int32     ` + "`json:\"int32\"`" + `
+	Int64   int64     ` + "`json:\"int64\"`" + `
+	Rune    rune      ` + "`json:\"rune\"`" + `
+	Uint    uint      ` + "`json:\"uint\"`" + `
+	Byte    byte      ` + "`json:\"byte\"`" + `
+	Uint16  uint16    ` + "`json:\"uint16\"`" + `
+	Uint32  uint32    ` + "`json:\"uint32\"`" + `
+	Uint64  uint64    ` + "`json:\"uint64\"`" + `
+	Uintptr uintptr   ` + "`json:\"uintptr\"`" + `
+	Float32 float32   ` + "`json:\"float32\"`" + `
+	Float64 float64   ` + "`json:\"float64\"`" + `
+	Bytes   []byte    ` + "`json:\"bytes\"`" + `
+	Blob    Blob      ` + "`json:\"blob\"`" + `
+	Array   [4]byte   ` + "`json:\"array\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "Numbers")
+
+	_, schema := r.resolveSchema(decl)
+	props := schema.Properties
+
+	tests := []struct {
+		property string
+		typ      string
+		format   string
+		unsigned bool
+	}{
+		{property: "int", typ: "integer", format: "int64"},
+		{property: "int8", typ: "integer", format: "int32"},
+		{property: "int16", typ: "integer", format: "int32"},
+		{property: "int32", typ: "integer", format: "int32"},
+		{property: "int64", typ: "integer", format: "int64"},
+		{property: "rune", typ: "integer", format: "int32"},
+		{property: "uint", typ: "integer", format: "int64", unsigned: true},
+		{property: "byte", typ: "integer", format: "int32", unsigned: true},
+		{property: "uint16", typ: "integer", format: "int32", unsigned: true},
+		{property: "uint32", typ: "integer", format: "int64", unsigned: true},
+		{property: "uint64", typ: "integer", format: "int64", unsigned: true},
+		{property: "uintptr", typ: "integer", format: "int64", unsigned: true},
+		{property: "float32", typ: "number", format: "float"},
+		{property: "float64", typ: "number", format: "double"},
+		{property: "bytes", typ: "string", format: "byte"},
+	}
+	for _, test := range tests {
+		value := props[test.property].Value
+		if !value.Type.Is(test.typ) || value.Format != test.format {
+			t.Errorf("%s: expected %s/%s, got %v/%s", test.property, test.typ, test.format, value.Type, value.Format)
+		}
+		if unsigned := value.Min != nil && *value.Min == 0; unsigned != test.unsigned {
+			t.Errorf("%s: expected minimum 0 to be %v, got %v", test.property, test.unsigned, value.Min)
+		}
+	}
+	blob := r.schemas["Blob"].Value
+	if !blob.Type.Is("string") || blob.Format != "byte" {
+		t.Errorf("expected Blob to be a base64 string, got %v/%s", blob.Type, blob.Format)
+	}
+	if array := props["array"].Value; !array.Type.Is("array") || array.Items.Value.Format != "int32" {
+		t.Errorf("expected byte arrays to stay arrays of integers, got %v", array.Type)
+	}
+}
+
+func TestResolveSchema_NestedCollections(t *testing.T) {
+	t.Parallel()
+
+	other := checkTestPackage(t, "example.com/other", `package other
+type Tag struct {
+	Name string `+"`json:\"name\"`"+`
+}
+`)
+	pkg := checkTestPackage(t, "test", `package test
+import "example.com/other"
+type User struct{}
+type Lookup map[string][]User
+type Collections struct {
+	Matrix  [][]string                `+"`json:\"matrix\"`"+`
+	Counts  []map[string]int          `+"`json:\"counts\"`"+`
+	Tags    []other.Tag               `+"`json:\"tags\"`"+`
+	Users   map[string]User           `+"`json:\"users\"`"+`
+	Nested  map[string]map[string]*User `+"`json:\"nested\"`"+`
+	Any     map[string]any            `+"`json:\"any\"`"+`
+	Digest  [4]byte                   `+"`json:\"digest\"`"+`
+	Lookup  Lookup                    `+"`json:\"lookup\"`"+`
+}
+`, other)
+	r := newSchemaResolver([]*packages.Package{pkg}, schemaOptions{})
+	decl := r.decls[pkg.Types.Scope().Lookup("Collections").(*types.TypeName)]
+
+	_, schema := r.resolveSchema(decl)
+	props := schema.Properties
+
+	if items := props["matrix"].Value.Items.Value; !items.Type.Is("array") || !items.Items.Value.Type.Is("string") {
+		t.Errorf("expected matrix to be an array of arrays of strings, got %v", items)
+	}
+	counts := props["counts"].Value.Items.Value
+	if !counts.Type.Is("object") || !counts.AdditionalProperties.Schema.Value.Type.Is("integer") {
+		t.Errorf("expected counts items to be maps of integers, got %v", counts)
+	}
+	if ref := props["tags"].Value.Items.Ref; ref != "#/components/schemas/Tag" {
+		t.Errorf("expected tags items to be $ref to Tag, got %q", ref)
+	}
+	if ref := props["users"].Value.AdditionalProperties.Schema.Ref; ref != "#/components/schemas/User" {
+		t.Errorf("expected users values to be $ref to User, got %q", ref)
+	}
+	nested := props["nested"].Value.AdditionalProperties.Schema.Value
+	if ref := nested.AdditionalProperties.Schema.Ref; ref != "#/components/schemas/User" {
+		t.Errorf("expected nested map values to be $ref to User, got %q", ref)
+	}
+	if values := props["any"].Value.AdditionalProperties; values.Has == nil || !*values.Has || values.Schema != nil {
+		t.Errorf("expected map of any to allow any value, got %v", values)
+	}
+	digest := props["digest"].Value
+	if digest.MinItems != 4 || digest.MaxItems == nil || *digest.MaxItems != 4 {
+		t.Errorf("expected digest to hold exactly 4 items, got %d..%v", digest.MinItems, digest.MaxItems)
+	}
+	lookup := r.schemas["Lookup"].Value
+	if !lookup.Type.Is("object") || lookup.AdditionalProperties.Schema.Value.Items.Ref != "#/components/schemas/User" {
+		t.Errorf("expected Lookup to be a map of User lists, got %v", lookup)
+	}
+}
+
+func TestResolveSchema_AnonymousStructs(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+type Order struct {
+	Meta struct {
+		Source string ` + "`json:\"source\" oapi_example:\"web\"`" + `
+		// oapi_maxLength:"8"
+		Channel string ` + "`json:\"channel,omitempty\"`" + `
+		Origin struct {
+			Country string ` + "`json:\"country\"`" + `
+		} ` + "`json:\"origin\"`" + `
+	} ` + "`json:\"meta\"`" + `
+	Items []struct {
+		SKU      string ` + "`json:\"sku\"`" + `
+		Quantity int    ` + "`json:\"quantity\" validate:\"min=1\"`" + `
+	} ` + "`json:\"items\"`" + `
+}
+`
+	r, decl := parseTypeDecl(t, src, "Order")
+
+	_, schema := r.resolveSchema(decl)
+
+	meta := schema.Properties["meta"].Value
+	if meta == nil || !meta.Type.Is("object") {
+		t.Fatalf("expected meta to be an inline object, got %v", schema.Properties["meta"])
+	}
+	if source := meta.Properties["source"].Value; source.Example != "web" {
+		t.Errorf("expected source example from the tag, got %v", source.Example)
+	}
+	if channel := meta.Properties["channel"].Value; channel.MaxLength == nil || *channel.MaxLength != 8 {
+		t.Errorf("expected channel maxLength from the doc annotation, got %v", channel.MaxLength)
+	}
+	if strings.Join(meta.Required, ",") != "source,origin" {
+		t.Errorf("expected source and origin to be required, got %v", meta.Required)
+	}
+	if origin := meta.Properties["origin"].Value; origin == nil || origin.Properties["country"] == nil {
+		t.Errorf("expected nested anonymous struct origin to be inlined, got %v", origin)
+	}
+	items := schema.Properties["items"].Value.Items.Value
+	if items == nil || items.Properties["sku"] == nil || *items.Properties["quantity"].Value.Min != 1 {
+		t.Errorf("expected items to be inline objects, got %v", items)
+	}
+	if len(r.schemas) != 0 {
+		t.Errorf("expected anonymous structs not to generate components, got %v", r.schemas)
+	}
+
+	r, decl = parseTypeDecl(t, src, "Order")
+	r.opts.hoistStructs = true
+	r.component(decl, decl.obj.Type())
+
+	order := r.schemas["Order"].Value
+	if ref := order.Properties["meta"].Ref; ref != "#/components/schemas/OrderMeta" {
+		t.Errorf("expected meta to be $ref to OrderMeta, got %q", ref)
+	}
+	if ref := order.Properties["items"].Value.Items.Ref; ref != "#/components/schemas/OrderItems" {
+		t.Errorf("expected items to be $ref to OrderItems, got %q", ref)
+	}
+	if ref := r.schemas["OrderMeta"].Value.Properties["origin"].Ref; ref != "#/components/schemas/OrderMetaOrigin" {
+		t.Errorf("expected origin to be $ref to OrderMetaOrigin, got %q", ref)
+	}
+	if _, ok := r.schemas["OrderMetaOrigin"]; !ok {
+		t.Errorf("expected OrderMetaOrigin component, got %v", r.schemas)
+	}
+}
+
+func TestWalkPackage_RecursiveTypesIndependentOfOrder(t *testing.T) {
+	t.Parallel()
+
+	decls := []string{
+		"// oapi:schema\ntype Tree struct {\n\tRoot *Node `json:\"root\"`\n}\n",
+		"// oapi:schema\ntype Node struct {\n\tChildren []*Node `json:\"children\"`\n\tParent *Tree `json:\"parent\"`\n\tLeaf *Leaf `json:\"leaf\"`\n}\n",
+		"type Leaf struct {\n\tNode *Node `json:\"node\"`\n}\n",
+	}
+	generated := []string{}
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}} {
+		src := "package test\n"
+		for _, i := range order {
+			src += decls[i]
+		}
+		pkg := checkTestPackage(t, "test", src)
+		schemas, diagnostics := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{})
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics %v", diagnostics)
+		}
+		if len(schemas) != 3 {
+			t.Fatalf("expected Tree, Node and Leaf components, got %v", schemas)
+		}
+		if ref := schemas["Leaf"].Value.Properties["node"].Ref; ref != "#/components/schemas/Node" {
+			t.Fatalf("expected the cycle to be broken by a $ref, got %q", ref)
+		}
+		out, err := json.Marshal(schemas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated = append(generated, string(out))
+	}
+	for _, out := range generated[1:] {
+		if out != generated[0] {
+			t.Errorf("expected the components not to depend on declaration order:\n%s\n%s", generated[0], out)
+		}
+	}
+}
+
+func TestResolveSchema_EmbeddedFlattening(t *testing.T) {
+	t.Parallel()
+
+	src := `package test
+import "time"
+type Audit struct {
+	CreatedBy string ` + "`json:\"createdBy\"`" + `
+	ID        string ` + "`json:\"id\"`" + `
+}
+type Base struct {
+	Audit
+	ID   string ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+type Tags struct {
+	Name string ` + "`json:\"name\"`" + `
+	Tags []string ` + "`json:\"tags\"`" + `
+}
+type Extra struct {
+	Note string ` + "`json:\"note\"`" + `
+}
+type Composed struct {
+	Kind string ` + "`json:\"kind\"`" + `
+}
+type Meta struct {
+	Version int ` + "`json:\"version\"`" + `
+}
+type audit struct {
+	Reviewer string ` + "`json:\"reviewer\"`" + `
+}
+type User struct {
+	Base
+	Tags
+	*Extra
+	audit
+	// oapi_allOf
+	Composed
+	Meta  ` + "`json:\"meta\"`" + `
+	Email string ` + "`json:\"email\"`" + `
+}
+type Event struct {
+	time.Time
+}
+`
+	r, decl := parseTypeDecl(t, src, "User")
+	r.opts.flattenEmbedded = true
+
+	_, schema := r.resolveSchema(decl)
+
+	if len(schema.AllOf) != 2 || schema.AllOf[0].Ref != "#/components/schemas/Composed" {
+		t.Fatalf("expected only the oapi_allOf embed to be composed, got %v", schema.AllOf)
+	}
+	props := schema.AllOf[1].Value.Properties
+	for _, name := range []string{"id", "createdBy", "tags", "note", "reviewer", "email"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("expected promoted property %s, got %v", name, props)
+		}
+	}
+	// Base.Name and Tags.Name are at the same depth and untagged alike
+	if _, ok := props["name"]; ok {
+		t.Error("expected conflicting name fields to annihilate each other")
+	}
+	if ref := props["meta"].Ref; ref != "#/components/schemas/Meta" {
+		t.Errorf("expected the tagged embed to be a property, got %v", props["meta"])
+	}
+	required := strings.Join(schema.Required, ",")
+	if required != "createdBy,id,reviewer,meta,email" {
+		t.Errorf("expected fields promoted through a pointer to be optional, got %v", schema.Required)
+	}
+
+	r, decl = parseTypeDecl(t, src, "Event")
+	r.opts.flattenEmbedded = true
+	_, event := r.resolveSchema(decl)
+	if len(event.AllOf) != 1 || event.AllOf[0].Value.Format != "date-time" {
+		t.Errorf("expected the embedded time.Time to keep its mapped schema, got %v", event.AllOf)
+	}
+}
+
+func TestWalkPackage_InterfaceVariants(t *testing.T) {
+	t.Parallel()
+
+	shapes := checkTestPackage(t, "example.com/shapes", `package shapes
+
+// oapi:schema
+type Square struct {
+	Side float64 `+"`json:\"side\"`"+`
+}
+
+func (Square) Shape() {}
+`)
+	pkg := checkTestPackage(t, "example.com/api", `package api
+import "example.com/shapes"
+
+// oapi:schema oapi_discriminator:kind
+type Shape interface{ Shape() }
+
+// oapi:schema
+type Drawing struct {
+	Shapes []Shape `+"`json:\"shapes\"`"+`
+}
+
+// oapi:schema
+type RoundedRect struct {
+	Radius float64 `+"`json:\"radius\"`"+`
+}
+
+func (*RoundedRect) Shape() {}
+
+// oapi:schema
+type Circle struct {
+	Radius float64 `+"`json:\"radius\"`"+`
+}
+
+func (Circle) Shape() {}
+
+// Triangle implements Shape but is not annotated.
+type Triangle struct{}
+
+func (Triangle) Shape() {}
+
+type Wrapper struct{ shapes.Square }
+`, shapes)
+
+	for _, strategy := range []NamingStrategy{NamingBare, NamingAuto} {
+		schemas, diagnostics := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{namingStrategy: strategy})
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics %v", diagnostics)
+		}
+
+		if ref := schemas["Drawing"].Value.Properties["shapes"].Value.Items.Ref; ref != "#/components/schemas/Shape" {
+			t.Errorf("expected shapes items to be $ref to Shape, got %q", ref)
+		}
+		shape := schemas["Shape"].Value
+		refs := []string{}
+		for _, variant := range shape.OneOf {
+			refs = append(refs, variant.Ref)
+		}
+		expected := "#/components/schemas/RoundedRect,#/components/schemas/Circle,#/components/schemas/Square"
+		if strings.Join(refs, ",") != expected {
+			t.Errorf("expected the annotated implementations as oneOf, got %v", refs)
+		}
+		if shape.Discriminator == nil || shape.Discriminator.PropertyName != "kind" {
+			t.Fatalf("expected a discriminator on kind, got %v", shape.Discriminator)
+		}
+		mapping := shape.Discriminator.Mapping
+		if len(mapping) != 3 || mapping["Circle"] != "#/components/schemas/Circle" || mapping["RoundedRect"] != "#/components/schemas/RoundedRect" {
+			t.Errorf("expected the mapping to be built from the variants, got %v", mapping)
+		}
+	}
+}
+
+func TestWalkPackage_SchemaOverrides(t *testing.T) {
+	t.Parallel()
+
+	pkg := checkTestPackage(t, "example.com/api", `package api
+
+import "github.com/getkin/kin-openapi/openapi3"
+
+// oapi:schema
+type Order struct {
+	ID     ID     `+"`json:\"id\"`"+`
+	Status Status `+"`json:\"status\"`"+`
+	Amount Amount `+"`json:\"amount\"`"+`
+}
+
+// ID is a ULID.
+//
+// oapi_schema_json:{"type":"string","minLength":26,"maxLength":26}
+type ID [16]byte
+
+// oapi_schema_json:{"type":
+type Status string
+
+// Amount has a schema method, which can not be called without a module.
+type Amount struct{ cents int64 }
+
+func (Amount) OpenAPISchema() *openapi3.Schema { return nil }
+`, openapi3Package(t))
+
+	schemas, diagnostics := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{})
+
+	id := schemas["ID"].Value
+	if !id.Type.Is("string") || id.MinLength != 26 || id.MaxLength == nil || *id.MaxLength != 26 {
+		t.Errorf("expected the annotated schema of ID, got %+v", id)
+	}
+	if id.Description != "" {
+		t.Errorf("expected the annotated schema to be taken verbatim, got description %q", id.Description)
+	}
+	if !schemas["Amount"].Value.Type.Is("object") {
+		t.Errorf("expected Amount to fall back to its structure, got %v", schemas["Amount"].Value.Type)
+	}
+	messages := []string{}
+	for _, d := range diagnostics {
+		messages = append(messages, d.Severity.String()+": "+d.Message)
+	}
+	expected := []string{
+		"error: invalid value of oapi_schema_json of example.com/api.Status: unexpected end of JSON input",
+		"warning: example.com/api.Amount.OpenAPISchema can not be called because the parsed packages do not belong to a module, annotate the type with oapi_schema_json instead",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+// openapi3Package type checks a stand-in for the openapi3 package, enough to
+// declare OpenAPISchema methods.
+func openapi3Package(t *testing.T) *packages.Package {
+	t.Helper()
+	return checkTestPackage(t, "github.com/getkin/kin-openapi/openapi3", `package openapi3
+
+type Schema struct{}
+`)
+}
+
+func TestWalkPackage_DiscriminatorKeysFromTypeNames(t *testing.T) {
+	t.Parallel()
+
+	legacy := checkTestPackage(t, "example.com/legacy", `package legacy
+
+// oapi:schema oapi_name:"LegacyCircle"
+type CircleShape struct{}
+
+func (CircleShape) Shape() {}
+`)
+	pkg := checkTestPackage(t, "example.com/api", `package api
+import _ "example.com/legacy"
+
+// oapi:schema oapi_discriminator:kind oapi_discriminator_mapped_parser:snake
+type Shape interface{ Shape() }
+
+// oapi:schema
+type CircleShape struct{}
+
+func (CircleShape) Shape() {}
+
+// oapi:schema
+type SquareShape struct{}
+
+func (SquareShape) Shape() {}
+`, legacy)
+
+	hash := importPathHash("example.com/api")
+	tests := []struct {
+		strategy NamingStrategy
+		shape    string
+		circle   string
+		square   string
+	}{
+		{strategy: NamingAuto, shape: "Shape", circle: "CircleShape", square: "SquareShape"},
+		{strategy: NamingPackageQualified, shape: "api.Shape", circle: "api.CircleShape", square: "api.SquareShape"},
+		{strategy: NamingImportPathHash, shape: "Shape_" + hash, circle: "CircleShape_" + hash, square: "SquareShape_" + hash},
+	}
+	for _, test := range tests {
+		schemas, diagnostics := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{namingStrategy: test.strategy})
+		if len(diagnostics) != 0 {
+			t.Fatalf("strategy %d: unexpected diagnostics %v", test.strategy, diagnostics)
+		}
+		mapping := schemas[test.shape].Value.Discriminator.Mapping
+		expected := openapi3.StringMap{
+			"circle_shape":  createRef(test.circle),
+			"legacy_circle": createRef("LegacyCircle"),
+			"square_shape":  createRef(test.square),
+		}
+		if !reflect.DeepEqual(mapping, expected) {
+			t.Errorf("strategy %d: expected mapping keys from the type names %v, got %v", test.strategy, expected, mapping)
+		}
+	}
+
+	shared := checkTestPackage(t, "example.com/shared", `package shared
+
+// oapi:schema
+type CircleShape struct{}
+
+func (CircleShape) Shape() {}
+`)
+	pkg = checkTestPackage(t, "example.com/api", `package api
+import _ "example.com/shared"
+
+// oapi:schema oapi_discriminator:kind oapi_discriminator_mapped_parser:snake
+type Shape interface{ Shape() }
+
+// oapi:schema
+type CircleShape struct{}
+
+func (CircleShape) Shape() {}
+`, shared)
+	_, diagnostics := walkPackageAndResolveSchemas([]*packages.Package{pkg}, schemaOptions{namingStrategy: NamingAuto})
+	if !strings.Contains(diagnostics.Error(), "discriminator value circle_shape of Shape maps to both") {
+		t.Errorf("expected a discriminator value conflict, got %v", diagnostics)
+	}
+}
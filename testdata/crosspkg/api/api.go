@@ -0,0 +1,13 @@
+package api
+
+import (
+	"github.com/nextap-solutions/openapi3Struct/testdata/crosspkg/billing"
+	"github.com/nextap-solutions/openapi3Struct/testdata/crosspkg/models"
+)
+
+// oapi:schema
+type Customer struct {
+	Name     string             `json:"name"`
+	Address  models.Address     `json:"address"`
+	Invoices []*billing.Invoice `json:"invoices"`
+}
@@ -0,0 +1,8 @@
+package billing
+
+import "github.com/nextap-solutions/openapi3Struct/testdata/crosspkg/models"
+
+type Invoice struct {
+	Number  string         `json:"number"`
+	Address models.Address `json:"address"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type Address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
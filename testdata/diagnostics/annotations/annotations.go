@@ -0,0 +1,18 @@
+package annotations
+
+// oapi:schema oapi_name:"Broken
+type Event struct {
+	Name string `json:"name"`
+}
+
+// oapi:schema oapi_nmae:"Typo"
+type User struct {
+	Name string `json:"name"`
+}
+
+// Group is not annotated, its annotations are checked all the same.
+//
+// oapi_discriminator:kind oapi_discriminator_mapped_parsr:snake
+type Group struct {
+	Kind string `json:"kind"`
+}
@@ -0,0 +1,6 @@
+package broken
+
+// oapi:schema
+type Event struct {
+	Name Missing `json:"name"`
+}
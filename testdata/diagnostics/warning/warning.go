@@ -0,0 +1,10 @@
+package warning
+
+// Event is published to subscribers.
+//
+// oapi:schema
+type Event struct {
+	Name string        `json:"name"`
+	Done chan struct{} `json:"-"`
+	Ack  func() error  `json:"ack"`
+}
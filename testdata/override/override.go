@@ -0,0 +1,40 @@
+package override
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// oapi:schema
+type Event struct {
+	At       Timestamp `json:"at"`
+	Timeout  Duration  `json:"timeout"`
+	Priority *Priority `json:"priority,omitempty"`
+}
+
+// Timestamp is encoded as the number of seconds since the Unix epoch.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(t.Unix(), 10)), nil
+}
+
+func (Timestamp) OpenAPISchema() *openapi3.Schema {
+	return openapi3.NewInt64Schema().WithMin(0)
+}
+
+// Duration is encoded like time.Duration.String.
+//
+// oapi_schema_json:{"type":"string","pattern":"^([0-9]+(ns|us|ms|s|m|h))+$"}
+type Duration time.Duration
+
+// Priority is encoded as a name.
+type Priority int
+
+func (p *Priority) OpenAPISchema() *openapi3.Schema {
+	return openapi3.NewStringSchema().WithEnum("low", "high")
+}
@@ -0,0 +1,50 @@
+package recursive
+
+// Tree is a tree of nodes, each node refers back to its tree.
+//
+// oapi:schema
+type Tree struct {
+	Root NodeRef `json:"root"`
+}
+
+// NodeRef points to a node of a tree.
+type NodeRef *Node
+
+// oapi:schema
+type Node struct {
+	Name     string     `json:"name"`
+	Children []*Node    `json:"children"`
+	Parent   *Tree      `json:"parent"`
+	Labels   Attributes `json:"labels"`
+}
+
+// Attributes are nested key value pairs.
+type Attributes map[string]Attributes
+
+// Graph is a directed graph, vertices and edges refer to each other.
+//
+// oapi:schema
+type Graph struct {
+	Vertices []*Vertex `json:"vertices"`
+}
+
+type Vertex struct {
+	ID    string  `json:"id"`
+	Edges []*Edge `json:"edges"`
+}
+
+type Edge struct {
+	From *Vertex `json:"from"`
+	To   *Vertex `json:"to"`
+}
+
+// oapi:schema
+type Category[T any] struct {
+	Value    T             `json:"value"`
+	Children []Category[T] `json:"children"`
+}
+
+// oapi:schema
+type Catalog struct {
+	Root Category[string] `json:"root"`
+}
@@ -0,0 +1,11 @@
+package versioned
+
+// oapi:schema
+type Release struct {
+	Name string `json:"name"`
+}
+
+// Draft is not annotated, no component is generated for it.
+type Draft struct {
+	Name string `json:"name"`
+}
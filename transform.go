@@ -0,0 +1,122 @@
+package openapi3Struct
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// KeyTransformer transforms the component name of a oneOf schema into its
+// discriminator mapping key, e.g. UserCreatedEvent into user_created_event.
+type KeyTransformer func(key string) string
+
+// keyTransformers are the transformers available to
+// oapi_discriminator_mapped_parser by name.
+var keyTransformers = map[string]KeyTransformer{
+	"snake":           func(key string) string { return joinWords(splitWords(key), "_", strings.ToLower) },
+	"SCREAMING_SNAKE": func(key string) string { return joinWords(splitWords(key), "_", strings.ToUpper) },
+	"upperSnake":      func(key string) string { return joinWords(splitWords(key), "_", strings.ToUpper) },
+	"kebab":           func(key string) string { return joinWords(splitWords(key), "-", strings.ToLower) },
+	"camel":           func(key string) string { return joinWords(splitWords(key), "", titleWord) },
+	"lowerCamel":      lowerCamel,
+	"lower":           strings.ToLower,
+	"upper":           strings.ToUpper,
+}
+
+// keyTransformerFactories are the transformers taking an argument, written
+// as name=argument.
+var keyTransformerFactories = map[string]func(arg string) KeyTransformer{
+	"trimPrefix": func(prefix string) KeyTransformer {
+		return func(key string) string { return strings.TrimPrefix(key, prefix) }
+	},
+	"trimSuffix": func(suffix string) KeyTransformer {
+		return func(key string) string { return strings.TrimSuffix(key, suffix) }
+	},
+}
+
+// keyTransformer parses the value of oapi_discriminator_mapped_parser, a comma
+// separated list of transformers applied in order, like
+// "trimSuffix=Event,snake". Custom transformers take precedence over the
+// built-in ones.
+func (r *schemaResolver) keyTransformer(parser string) (KeyTransformer, error) {
+	chain := []KeyTransformer{}
+	for _, name := range strings.Split(parser, ",") {
+		name = strings.TrimSpace(name)
+		if transformer, ok := r.opts.keyTransformers[name]; ok {
+			chain = append(chain, transformer)
+			continue
+		}
+		if transformer, ok := keyTransformers[name]; ok {
+			chain = append(chain, transformer)
+			continue
+		}
+		factoryName, arg, _ := strings.Cut(name, "=")
+		factory, ok := keyTransformerFactories[factoryName]
+		if !ok {
+			return nil, fmt.Errorf("unknown discriminator mapping transformer %q", name)
+		}
+		chain = append(chain, factory(arg))
+	}
+
+	return func(key string) string {
+		for _, transformer := range chain {
+			key = transformer(key)
+		}
+		return key
+	}, nil
+}
+
+// splitWords splits an identifier into its words, at separators and case
+// changes, so HTTPServerID_v2 becomes HTTP, Server, ID and v2.
+func splitWords(s string) []string {
+	words := []string{}
+	runes := []rune(s)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == '_' || c == '-' || c == '.' || unicode.IsSpace(c) {
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(c) {
+			continue
+		}
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+func joinWords(words []string, sep string, transform func(string) string) string {
+	for i, word := range words {
+		words[i] = transform(word)
+	}
+	return strings.Join(words, sep)
+}
+
+func titleWord(word string) string {
+	return capitalize(strings.ToLower(word))
+}
+
+func lowerCamel(key string) string {
+	words := splitWords(key)
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+			continue
+		}
+		words[i] = titleWord(word)
+	}
+	return strings.Join(words, "")
+}
@@ -0,0 +1,53 @@
+package openapi3Struct
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyTransformer(t *testing.T) {
+	t.Parallel()
+
+	r := &schemaResolver{opts: schemaOptions{keyTransformers: map[string]KeyTransformer{
+		"reverse": func(key string) string {
+			runes := []rune(key)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes)
+		},
+		"lower": strings.ToUpper,
+	}}}
+
+	tests := []struct {
+		parser   string
+		key      string
+		expected string
+	}{
+		{parser: "snake", key: "UserCreatedEvent", expected: "user_created_event"},
+		{parser: "SCREAMING_SNAKE", key: "HTTPServerID", expected: "HTTP_SERVER_ID"},
+		{parser: "upperSnake", key: "RoundedRect", expected: "ROUNDED_RECT"},
+		{parser: "kebab", key: "userCreated2Event", expected: "user-created2-event"},
+		{parser: "camel", key: "user_created_event", expected: "UserCreatedEvent"},
+		{parser: "lowerCamel", key: "UserCreatedEvent", expected: "userCreatedEvent"},
+		{parser: "upper", key: "Circle", expected: "CIRCLE"},
+		{parser: "trimSuffix=Event,snake", key: "UserCreatedEvent", expected: "user_created"},
+		{parser: "trimPrefix=Event, kebab", key: "EventUserCreated", expected: "user-created"},
+		{parser: "reverse", key: "abc", expected: "cba"},
+		{parser: "lower", key: "Circle", expected: "CIRCLE"},
+	}
+	for _, test := range tests {
+		transform, err := r.keyTransformer(test.parser)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.parser, err)
+			continue
+		}
+		if key := transform(test.key); key != test.expected {
+			t.Errorf("%s: expected %s to become %s, got %s", test.parser, test.key, test.expected, key)
+		}
+	}
+
+	if _, err := r.keyTransformer("snake,shout"); err == nil {
+		t.Error("expected an error for an unknown transformer")
+	}
+}
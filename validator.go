@@ -0,0 +1,285 @@
+package openapi3Struct
+
+import (
+	"go/types"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// validateKind is the kind of value a validate rule applies to, which decides
+// the schema keyword it translates to.
+type validateKind int
+
+const (
+	validateOther validateKind = iota
+	validateString
+	validateNumber
+	validateArray
+	validateMap
+)
+
+// validateFormats maps validate rules to the string format they check
+var validateFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"http_url": "uri",
+	"uuid":     "uuid",
+	"uuid4":    "uuid",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"ip":       "ip",
+	"hostname": "hostname",
+	"base64":   "byte",
+	"datetime": "date-time",
+}
+
+// validatePatterns maps validate rules to the pattern they check
+var validatePatterns = map[string]string{
+	"alpha":    `^[a-zA-Z]+$`,
+	"alphanum": `^[a-zA-Z0-9]+$`,
+	"numeric":  `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":   `^[0-9]+$`,
+	"hexcolor": `^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`,
+	"e164":     `^\+[1-9]?[0-9]{7,14}$`,
+}
+
+func validateKindOf(typ types.Type) validateKind {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		if t.Info()&types.IsString != 0 {
+			return validateString
+		}
+		if t.Info()&types.IsNumeric != 0 {
+			return validateNumber
+		}
+	case *types.Slice, *types.Array:
+		return validateArray
+	case *types.Map:
+		return validateMap
+	}
+
+	return validateOther
+}
+
+// hasValidateRequired reports whether tag holds a validate:"required" rule
+func hasValidateRequired(tag string) bool {
+	value, ok := reflect.StructTag(tag).Lookup("validate")
+	if !ok {
+		return false
+	}
+	rules, _, _ := strings.Cut(value, ",dive")
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyValidateTag translates the github.com/go-playground/validator rules of
+// the validate struct tag into schema constraints on fieldSchema, a field of
+// type typ. Rules following dive apply to the items of slices and arrays.
+func (r *schemaResolver) applyValidateTag(fieldSchema *openapi3.SchemaRef, typ types.Type, tag string) *openapi3.SchemaRef {
+	value, ok := reflect.StructTag(tag).Lookup("validate")
+	if !ok || value == "" || value == "-" {
+		return fieldSchema
+	}
+
+	return r.applyValidateRules(fieldSchema, typ, strings.Split(value, ","))
+}
+
+func (r *schemaResolver) applyValidateRules(fieldSchema *openapi3.SchemaRef, typ types.Type, rules []string) *openapi3.SchemaRef {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	kind := validateKindOf(typ)
+	schema := withSiblings(fieldSchema)
+	changed := false
+	for i, rule := range rules {
+		if rule == "dive" {
+			elem, ok := collectionElem(typ)
+			if !ok || schema.Items == nil {
+				break
+			}
+			items := r.applyValidateRules(schema.Items, elem, rules[i+1:])
+			changed = changed || items != schema.Items
+			schema.Items = items
+			break
+		}
+		// alternatives can not be expressed as constraints of a single schema
+		if strings.Contains(rule, "|") {
+			continue
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		if applyValidateRule(schema, kind, name, param) {
+			changed = true
+		}
+	}
+	if !changed {
+		return fieldSchema
+	}
+
+	return openapi3.NewSchemaRef("", schema)
+}
+
+func collectionElem(typ types.Type) (types.Type, bool) {
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		return t.Elem(), true
+	case *types.Array:
+		return t.Elem(), true
+	}
+	return nil, false
+}
+
+// applyValidateRule applies a single validate rule to schema and reports
+// whether it translated to a schema constraint.
+func applyValidateRule(schema *openapi3.Schema, kind validateKind, name, param string) bool {
+	if format, ok := validateFormats[name]; ok && kind == validateString {
+		schema.Format = format
+		return true
+	}
+	if pattern, ok := validatePatterns[name]; ok && kind == validateString {
+		schema.Pattern = pattern
+		return true
+	}
+
+	switch name {
+	case "min", "gte":
+		return setBound(schema, kind, param, true, false)
+	case "max", "lte":
+		return setBound(schema, kind, param, false, false)
+	case "gt":
+		return setBound(schema, kind, param, true, true)
+	case "lt":
+		return setBound(schema, kind, param, false, true)
+	case "eq":
+		// eq checks the value of strings and numbers and the length of anything else
+		if kind == validateString || kind == validateNumber {
+			value, ok := enumParam(kind, param)
+			if ok {
+				schema.Enum = []any{value}
+			}
+			return ok
+		}
+		return setBound(schema, kind, param, true, false) && setBound(schema, kind, param, false, false)
+	case "len":
+		return setBound(schema, kind, param, true, false) && setBound(schema, kind, param, false, false)
+	case "oneof":
+		values := []any{}
+		for _, param := range strings.Fields(param) {
+			if value, ok := enumParam(kind, param); ok {
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return false
+		}
+		schema.Enum = values
+		return true
+	case "unique":
+		if kind == validateArray {
+			schema.UniqueItems = true
+			return true
+		}
+	case "startswith":
+		if kind == validateString {
+			schema.Pattern = "^" + regexp.QuoteMeta(param)
+			return true
+		}
+	case "endswith":
+		if kind == validateString {
+			schema.Pattern = regexp.QuoteMeta(param) + "$"
+			return true
+		}
+	case "contains":
+		if kind == validateString {
+			schema.Pattern = regexp.QuoteMeta(param)
+			return true
+		}
+	}
+
+	return false
+}
+
+// setBound sets the lower or upper bound of schema from a validate rule
+// parameter, lengths are counted in characters, items or properties
+// depending on kind. It reports whether the bound applies to kind.
+func setBound(schema *openapi3.Schema, kind validateKind, param string, lower bool, exclusive bool) bool {
+	if kind == validateNumber {
+		value, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false
+		}
+		if lower {
+			schema.Min = &value
+			schema.ExclusiveMin = exclusive
+		} else {
+			schema.Max = &value
+			schema.ExclusiveMax = exclusive
+		}
+		return true
+	}
+
+	value, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return false
+	}
+	if exclusive {
+		if lower {
+			value++
+		} else if value > 0 {
+			value--
+		}
+	}
+	switch kind {
+	case validateString:
+		if lower {
+			schema.MinLength = value
+		} else {
+			schema.MaxLength = &value
+		}
+	case validateArray:
+		if lower {
+			schema.MinItems = value
+		} else {
+			schema.MaxItems = &value
+		}
+	case validateMap:
+		if lower {
+			schema.MinProps = value
+		} else {
+			schema.MaxProps = &value
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+func enumParam(kind validateKind, param string) (any, bool) {
+	switch kind {
+	case validateString:
+		return param, true
+	case validateNumber:
+		if value, err := strconv.ParseInt(param, 10, 64); err == nil {
+			return value, true
+		}
+		if value, err := strconv.ParseFloat(param, 64); err == nil {
+			return value, true
+		}
+		return nil, false
+	}
+
+	return nil, false
+}